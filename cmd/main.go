@@ -1,42 +1,110 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"procrastigo/internal/config"
 	"procrastigo/internal/handlers"
-	"procrastigo/internal/storage"
 	"procrastigo/pkg/logger"
-
-	"github.com/gorilla/mux"
+	"syscall"
+	"time"
 )
 
 func main() {
-	cfg := config.Load()
+	configPath := flag.String("config", config.DefaultPath, "path to the YAML configuration file")
+	flag.Usage = usage
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	logger.Init(cfg.LogLevel())
 
-	store := storage.NewMemoryStorage()
-	if err := store.LoadFromFile("data/excuses.json"); err != nil {
-		log.Printf("Failed to load excuses: %v", err)
+	switch cmd := flag.Arg(0); cmd {
+	case "", "serve":
+		serve(cfg)
+	case "migrate":
+		if err := runMigrate(cfg, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+	case "export":
+		if err := runExport(cfg, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+	case "import":
+		if err := runImport(cfg, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", cmd)
+		usage()
+		os.Exit(2)
 	}
+}
 
-	excuseHandler := handlers.NewExcuseHandler(store)
-	statsHandler := handlers.NewStatsHandler(store)
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: procrastigo [--config path] [command]
 
-	router := mux.NewRouter()
+Commands:
+  serve                    start the HTTP server (default)
+  migrate up               apply pending database migrations
+  migrate down [N]         revert the last N migrations (default 1)
+  migrate status           show applied and pending migrations
+  export [flags]           write all excuses to a file or stdout
+                           (--format json|csv|ndjson, --output file)
+  import [flags] FILE      load excuses from a JSON, CSV or NDJSON file
+                           (--format, --on-conflict upsert|skip|fail,
+                            --mode strict|lenient, --dry-run)
 
-	v1 := router.PathPrefix("/api/v1").Subrouter()
+Flags:
+`)
+	flag.PrintDefaults()
+}
+
+func serve(cfg *config.Config) {
+	store, cat, err := openStorage(context.Background(), cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			logger.Error.Printf("Failed to close storage: %v", err)
+		}
+	}()
 
-	v1.HandleFunc("/excuses/random", excuseHandler.GetRandomExcuse).Methods("GET")
-	v1.HandleFunc("/excuses", excuseHandler.GetExcuses).Methods("GET")
-	v1.HandleFunc("/excuses", excuseHandler.CreateExcuse).Methods("POST")
+	router := handlers.NewRouter(store, cat, cfg)
 
-	v1.HandleFunc("/stats", statsHandler.GetStats).Methods("GET")
+	server := &http.Server{Addr: cfg.ServerAddress(), Handler: router}
 
-	router.Use(handlers.LoggingMiddleware)
-	router.Use(handlers.CORSMiddleware)
+	// по SIGINT/SIGTERM дожидаемся текущих запросов и только потом
+	// закрываем хранилище (defer выше), чтобы memory хранилище успело
+	// записать их в журнал и сделать снимок
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error.Printf("Graceful shutdown failed: %v", err)
+		}
+	}()
 
 	log.Printf("🚀 Server starting on %s", cfg.ServerAddress())
-	log.Fatal(http.ListenAndServe(cfg.ServerAddress(), router))
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		store.Close()
+		log.Fatal(err)
+	}
+	// ListenAndServe возвращается сразу после начала Shutdown
+	<-stopped
+	log.Printf("Server stopped")
 }
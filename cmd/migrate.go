@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"procrastigo/internal/config"
+	"procrastigo/internal/storage"
+	"strconv"
+)
+
+// runMigrate выполняет подкоманду migrate: up, down [N] или status.
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("migrate: expected up, down [N] or status")
+	}
+
+	ctx := context.Background()
+
+	migrator, db, err := storage.OpenMigrator(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		fmt.Printf("Applied %d migration(s)\n", applied)
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("migrate down: N must be a positive integer, got %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		fmt.Printf("Reverted %d migration(s)\n", reverted)
+		return err
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		for _, st := range statuses {
+			state := "pending"
+			if st.Applied {
+				state = "applied " + st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-30s %s\n", st.Version, st.Name, state)
+		}
+		return err
+
+	default:
+		return fmt.Errorf("migrate: unknown action %q", args[0])
+	}
+}
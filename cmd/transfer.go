@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"procrastigo/internal/catalog"
+	"procrastigo/internal/config"
+	"procrastigo/internal/storage"
+	"procrastigo/internal/transfer"
+)
+
+// runExport выполняет подкоманду export: выгружает все оправдания
+// настроенного хранилища в файл или stdout.
+func runExport(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", "", "json, csv or ndjson (default: from the output file extension, json for stdout)")
+	output := flags.String("output", "", "output file (default: stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *format == "" {
+		*format = transfer.FormatFromPath(*output)
+	}
+	if !transfer.ValidFormat(*format) {
+		return fmt.Errorf("export: unknown format %q", *format)
+	}
+
+	if ephemeral(cfg) {
+		fmt.Fprintln(os.Stderr, "warning: memory storage without storage.persistence.dir starts empty; nothing to export")
+	}
+
+	ctx := context.Background()
+	store, _, err := openStorage(ctx, withoutSeed(cfg))
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	defer store.Close()
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("export: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	count, err := transfer.Export(ctx, store, w, *format)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d excuse(s)\n", count)
+	return nil
+}
+
+// runImport выполняет подкоманду import: загружает оправдания из файла
+// в настроенное хранилище и печатает итог.
+func runImport(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", "", "json, csv or ndjson (default: from the file extension)")
+	onConflict := flags.String("on-conflict", transfer.ConflictFail, "what to do with existing ids: upsert, skip or fail")
+	mode := flags.String("mode", storage.LoadStrict, "invalid records: strict aborts the import, lenient skips them")
+	dryRun := flags.Bool("dry-run", false, "validate and report without writing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("import: expected exactly one input file")
+	}
+	path := flags.Arg(0)
+
+	if *format == "" {
+		*format = transfer.FormatFromPath(path)
+	}
+	if !transfer.ValidFormat(*format) {
+		return fmt.Errorf("import: unknown format %q", *format)
+	}
+	if !transfer.ValidConflictPolicy(*onConflict) {
+		return fmt.Errorf("import: unknown conflict policy %q", *onConflict)
+	}
+	if *mode != storage.LoadStrict && *mode != storage.LoadLenient {
+		return fmt.Errorf("import: unknown mode %q", *mode)
+	}
+
+	if ephemeral(cfg) && !*dryRun {
+		return errors.New("import: memory storage without storage.persistence.dir would lose the data on exit; " +
+			"set storage.persistence.dir, use another driver or --dry-run")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	defer f.Close()
+
+	ctx := context.Background()
+	store, cat, err := openStorage(ctx, withoutSeed(cfg))
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	defer store.Close()
+
+	report, err := transfer.Import(ctx, store, f, transfer.ImportOptions{
+		Format:     *format,
+		OnConflict: *onConflict,
+		Mode:       *mode,
+		Catalog:    cat,
+		DryRun:     *dryRun,
+	})
+	if report != nil {
+		for _, re := range report.Errors {
+			fmt.Fprintf(os.Stderr, "invalid: %s\n", re)
+		}
+		fmt.Println(report)
+	}
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	return nil
+}
+
+// withoutSeed возвращает копию cfg без заполнения из storage.seed_file:
+// import и export работают только с тем, что уже есть в хранилище.
+func withoutSeed(cfg *config.Config) *config.Config {
+	c := *cfg
+	c.Storage.SeedPolicy = storage.SeedNever
+	return &c
+}
+
+// ephemeral сообщает, что хранилище живет только в памяти процесса
+func ephemeral(cfg *config.Config) bool {
+	return cfg.StorageDriver() == storage.DriverMemory && cfg.Storage.Persistence.Dir == ""
+}
+
+// openStorage загружает справочники и открывает хранилище из cfg.
+func openStorage(ctx context.Context, cfg *config.Config) (storage.Storage, *catalog.Catalog, error) {
+	cat, err := catalog.Load(cfg.Catalog.File)
+	if err != nil {
+		return nil, nil, err
+	}
+	store, err := storage.New(ctx, cfg, cat)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize %s storage: %w", cfg.StorageDriver(), err)
+	}
+	return store, cat, nil
+}
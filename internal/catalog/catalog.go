@@ -0,0 +1,170 @@
+// Package catalog хранит справочники допустимых категорий, языков и
+// уровней серьезности оправданий с локализованными названиями.
+// Справочники загружаются из YAML файла (см. configs/catalog.yaml).
+package catalog
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Виды справочников
+const (
+	KindCategory = "category"
+	KindLanguage = "language"
+	KindSeverity = "severity"
+)
+
+// Entry - значение справочника
+type Entry struct {
+	Code  string            `yaml:"code"`
+	Names map[string]string `yaml:"names"` // язык интерфейса -> название
+}
+
+// Item - значение справочника с названием на запрошенном языке
+type Item struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// Defaults - значения полей оправдания по умолчанию
+type Defaults struct {
+	Category string `yaml:"category"`
+	Language string `yaml:"language"`
+	Severity string `yaml:"severity"`
+}
+
+// Catalog - набор справочников. После загрузки не изменяется и безопасен
+// для одновременного использования.
+type Catalog struct {
+	DisplayLanguage string   `yaml:"display_language"`
+	Defaults        Defaults `yaml:"defaults"`
+	Categories      []Entry  `yaml:"categories"`
+	Languages       []Entry  `yaml:"languages"`
+	Severities      []Entry  `yaml:"severities"`
+
+	index map[string]map[string]bool
+}
+
+// Load читает справочники из YAML файла и проверяет их.
+func Load(path string) (*Catalog, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog: %w", err)
+	}
+
+	var c Catalog
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&c); err != nil {
+		return nil, fmt.Errorf("failed to parse catalog %s: %w", path, err)
+	}
+
+	if err := c.init(); err != nil {
+		return nil, fmt.Errorf("invalid catalog %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// New создает каталог из готовых значений (например, в тестах).
+func New(defaults Defaults, categories, languages, severities []Entry) (*Catalog, error) {
+	c := &Catalog{
+		Defaults:   defaults,
+		Categories: categories,
+		Languages:  languages,
+		Severities: severities,
+	}
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// init приводит коды к нижнему регистру, строит индекс и проверяет,
+// что справочники не пусты, коды уникальны, а значения по умолчанию существуют.
+func (c *Catalog) init() error {
+	c.index = make(map[string]map[string]bool)
+	kinds := map[string][]Entry{
+		KindCategory: c.Categories,
+		KindLanguage: c.Languages,
+		KindSeverity: c.Severities,
+	}
+
+	for kind, entries := range kinds {
+		if len(entries) == 0 {
+			return fmt.Errorf("%s list is empty", kind)
+		}
+		codes := make(map[string]bool, len(entries))
+		for i := range entries {
+			code := strings.ToLower(strings.TrimSpace(entries[i].Code))
+			if code == "" {
+				return fmt.Errorf("%s #%d has empty code", kind, i)
+			}
+			if codes[code] {
+				return fmt.Errorf("duplicate %s %q", kind, code)
+			}
+			entries[i].Code = code
+			codes[code] = true
+		}
+		c.index[kind] = codes
+	}
+
+	c.Defaults.Category = strings.ToLower(c.Defaults.Category)
+	c.Defaults.Language = strings.ToLower(c.Defaults.Language)
+	c.Defaults.Severity = strings.ToLower(c.Defaults.Severity)
+	for kind, code := range map[string]string{
+		KindCategory: c.Defaults.Category,
+		KindLanguage: c.Defaults.Language,
+		KindSeverity: c.Defaults.Severity,
+	} {
+		if !c.index[kind][code] {
+			return fmt.Errorf("default %s %q is not in the %s list", kind, code, kind)
+		}
+	}
+
+	if c.DisplayLanguage == "" {
+		c.DisplayLanguage = c.Defaults.Language
+	}
+	return nil
+}
+
+// Valid сообщает, есть ли code (без учета регистра) в справочнике kind.
+func (c *Catalog) Valid(kind, code string) bool {
+	return c.index[kind][strings.ToLower(code)]
+}
+
+func (c *Catalog) ValidCategory(code string) bool { return c.Valid(KindCategory, code) }
+func (c *Catalog) ValidLanguage(code string) bool { return c.Valid(KindLanguage, code) }
+func (c *Catalog) ValidSeverity(code string) bool { return c.Valid(KindSeverity, code) }
+
+// Items возвращает справочник kind с названиями на языке lang. Если
+// названия на lang нет, используется display_language, затем код.
+func (c *Catalog) Items(kind, lang string) []Item {
+	var entries []Entry
+	switch kind {
+	case KindCategory:
+		entries = c.Categories
+	case KindLanguage:
+		entries = c.Languages
+	case KindSeverity:
+		entries = c.Severities
+	}
+
+	lang = strings.ToLower(lang)
+	items := make([]Item, len(entries))
+	for i, entry := range entries {
+		name := entry.Names[lang]
+		if name == "" {
+			name = entry.Names[c.DisplayLanguage]
+		}
+		if name == "" {
+			name = entry.Code
+		}
+		items[i] = Item{Code: entry.Code, Name: name}
+	}
+	return items
+}
@@ -0,0 +1,84 @@
+package catalog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadShippedCatalog(t *testing.T) {
+	c, err := Load("../../configs/catalog.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// значения из data/excuses.json и api/v1/openapi.yaml
+	for kind, codes := range map[string][]string{
+		KindCategory: {"general", "tech", "urgent", "family"},
+		KindLanguage: {"ru", "en", "es", "fr"},
+		KindSeverity: {"low", "medium", "high", "critical"},
+	} {
+		for _, code := range codes {
+			if !c.Valid(kind, code) {
+				t.Errorf("%s %q is not in the shipped catalog", kind, code)
+			}
+		}
+	}
+	if !c.ValidCategory("TECH") {
+		t.Error("codes must be case-insensitive")
+	}
+	if c.ValidCategory("nope") {
+		t.Error("unknown category accepted")
+	}
+}
+
+func TestItemsFallback(t *testing.T) {
+	c, err := New(Defaults{Category: "work", Language: "ru", Severity: "low"},
+		[]Entry{{Code: "work", Names: map[string]string{"ru": "Работа", "en": "Work"}}, {Code: "misc"}},
+		[]Entry{{Code: "ru"}},
+		[]Entry{{Code: "low"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		lang string
+		want []Item
+	}{
+		{"en", []Item{{"work", "Work"}, {"misc", "misc"}}},
+		{"fr", []Item{{"work", "Работа"}, {"misc", "misc"}}},
+		{"", []Item{{"work", "Работа"}, {"misc", "misc"}}},
+	}
+	for _, tc := range cases {
+		got := c.Items(KindCategory, tc.lang)
+		if len(got) != len(tc.want) {
+			t.Fatalf("lang %q: got %v, want %v", tc.lang, got, tc.want)
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("lang %q: got %v, want %v", tc.lang, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestNewRejectsInvalidCatalog(t *testing.T) {
+	one := []Entry{{Code: "x"}}
+	cases := []struct {
+		name       string
+		defaults   Defaults
+		categories []Entry
+		want       string
+	}{
+		{"empty list", Defaults{"x", "x", "x"}, nil, "category list is empty"},
+		{"duplicate", Defaults{"x", "x", "x"}, []Entry{{Code: "x"}, {Code: "X"}}, "duplicate category"},
+		{"empty code", Defaults{"x", "x", "x"}, []Entry{{Code: " "}}, "empty code"},
+		{"unknown default", Defaults{"y", "x", "x"}, one, "default category"},
+	}
+	for _, tc := range cases {
+		_, err := New(tc.defaults, tc.categories, one, one)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want error containing %q", tc.name, err, tc.want)
+		}
+	}
+}
@@ -3,13 +3,21 @@ package config
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+	_ "time/tzdata" // часовые пояса доступны и в образах без zoneinfo
 
 	"gopkg.in/yaml.v3"
 )
 
 type serverCfg struct {
-	Host string `yaml:"host"`
-	Port int    `yaml:"port"`
+	Host           string        `yaml:"host"`
+	Port           int           `yaml:"port"`
+	StorageTimeout time.Duration `yaml:"storage_timeout"` // лимит на обращения к хранилищу в рамках запроса
+	PageSize       int           `yaml:"page_size"`       // размер страницы списка по умолчанию
+	MaxPageSize    int           `yaml:"max_page_size"`   // верхняя граница limit
 }
 
 type loggingCfg struct {
@@ -17,93 +25,332 @@ type loggingCfg struct {
 }
 
 type databaseCfg struct { // <--- НОВАЯ СТРУКТУРА
-	Host     string `yaml:"host"`
-	Port     int    `yaml:"port"`
-	User     string `yaml:"user"`
-	Password string `yaml:"password"`
-	DBName   string `yaml:"dbname"`
-	SSLMode  string `yaml:"sslmode"`
+	Host        string `yaml:"host"`
+	Port        int    `yaml:"port"`
+	User        string `yaml:"user"`
+	Password    string `yaml:"password"`
+	DBName      string `yaml:"dbname"`
+	SSLMode     string `yaml:"sslmode"`
+	AutoMigrate bool   `yaml:"auto_migrate"` // применять миграции при старте
+}
+
+// persistenceCfg - сохранение memory хранилища на диск
+type persistenceCfg struct {
+	Dir              string        `yaml:"dir"`               // пусто - данные живут только в памяти
+	Fsync            string        `yaml:"fsync"`             // always | interval | never
+	SnapshotInterval time.Duration `yaml:"snapshot_interval"` // 0 - снимок только при остановке
+}
+
+// sqliteCfg - настройки драйвера sqlite
+type sqliteCfg struct {
+	Path string `yaml:"path"` // файл базы; создается при первом запуске
+}
+
+// storageCfg описывает выбор бэкенда хранилища и начальные данные.
+type storageCfg struct {
+	Driver     string `yaml:"driver"`      // memory | postgres | sqlite
+	SeedFile   string `yaml:"seed_file"`   // файл для заполнения пустого хранилища
+	SeedMode   string `yaml:"seed_mode"`   // strict | lenient
+	SeedPolicy string `yaml:"seed_policy"` // if_empty | always | never
+
+	Persistence persistenceCfg `yaml:"persistence"` // только для driver: memory
+	SQLite      sqliteCfg      `yaml:"sqlite"`      // только для driver: sqlite
+}
+
+// randomCfg - настройки выбора случайного оправдания
+type randomCfg struct {
+	DefaultMode   string  `yaml:"default_mode"`   // uniform | weighted | fresh
+	WeightFloor   float64 `yaml:"weight_floor"`   // минимальный вес в режиме weighted
+	MaxCandidates int     `yaml:"max_candidates"` // сколько лучших оправданий учитывает weighted
+	HistorySize   int     `yaml:"history_size"`   // сколько последних показов помнит fresh
+	MaxClients    int     `yaml:"max_clients"`    // сколько клиентов помнит история показов
+}
+
+// dailyCfg - настройки "оправдания дня"
+type dailyCfg struct {
+	Timezone string `yaml:"timezone"` // IANA имя, например Europe/Moscow
+}
+
+// dedupCfg - проверка новых оправданий на почти одинаковые
+type dedupCfg struct {
+	Threshold float64 `yaml:"threshold"` // сходство от 0 до 1; 0 - проверка выключена
+}
+
+// adminCfg - доступ к /api/v1/admin
+type adminCfg struct {
+	Token string `yaml:"token"` // Authorization: Bearer <token>; пусто - админ API выключен, правка открыта
+}
+
+// moderationCfg - проверка новых оправданий модератором
+type moderationCfg struct {
+	Enabled bool `yaml:"enabled"` // новые оправдания скрыты, пока их не одобрят через админ API
+}
+
+// policyCfg - проверка текста новых и измененных оправданий (см. internal/policy)
+type policyCfg struct {
+	MinLength   int                 `yaml:"min_length"`   // в символах; 0 - без ограничения
+	MaxLength   int                 `yaml:"max_length"`   // в символах; 0 - без ограничения
+	Links       string              `yaml:"links"`        // allow | strip | reject - ссылки и адреса почты
+	BannedWords map[string][]string `yaml:"banned_words"` // язык -> слова; проверяются все списки; "слово*" - все слова с этим началом
+}
+
+// votingCfg - как различать голосующих (см. POST /excuses/{id}/rate)
+type votingCfg struct {
+	APIKeys      []string `yaml:"api_keys"`      // ключи X-API-Key, которые голосуют от своего имени; другие ключи игнорируются
+	CookieSecret string   `yaml:"cookie_secret"` // ключ подписи cookie procrastigo_voter; пусто - случайный при каждом запуске
+}
+
+// catalogCfg - откуда брать справочники категорий, языков и серьезности
+type catalogCfg struct {
+	File string `yaml:"file"` // YAML файл справочников (см. configs/catalog.yaml)
 }
 
 type Config struct {
-	Server   serverCfg   `yaml:"server"`
-	Logging  loggingCfg  `yaml:"logging"`
-	Database databaseCfg `yaml:"database"` // <--- ДОБАВЛЕНО
+	Server     serverCfg     `yaml:"server"`
+	Logging    loggingCfg    `yaml:"logging"`
+	Database   databaseCfg   `yaml:"database"` // <--- ДОБАВЛЕНО
+	Storage    storageCfg    `yaml:"storage"`
+	Random     randomCfg     `yaml:"random"`
+	Daily      dailyCfg      `yaml:"daily"`
+	Catalog    catalogCfg    `yaml:"catalog"`
+	Dedup      dedupCfg      `yaml:"dedup"`
+	Admin      adminCfg      `yaml:"admin"`
+	Moderation moderationCfg `yaml:"moderation"`
+	Policy     policyCfg     `yaml:"policy"`
+	Voting     votingCfg     `yaml:"voting"`
 }
 
-// Load loads configuration from configs/config.yaml if present,
-// otherwise returns default configuration.
-func Load() *Config {
+// DefaultPath - путь к файлу конфигурации по умолчанию
+const DefaultPath = "configs/config.yaml"
+
+// Load loads configuration from the YAML file at path, starting from the
+// default configuration. Environment variables (see envBindings) are applied
+// on top of file values, and the result is validated. Every problem found is
+// reported at once in a *ValidationError. A missing file is only tolerated
+// for DefaultPath.
+func Load(path string) (*Config, error) {
 	cfg := &Config{
 		Server: serverCfg{
-			Host: "0.0.0.0",
-			Port: 8080,
+			Host:           "0.0.0.0",
+			Port:           8080,
+			StorageTimeout: 5 * time.Second,
+			PageSize:       20,
+			MaxPageSize:    100,
 		},
 		Logging: loggingCfg{
 			Level: "info",
 		},
 		Database: databaseCfg{ // <--- ЗНАЧЕНИЯ ПО УМОЛЧАНИЮ
-			Host:     "localhost",
-			Port:     5432,
-			User:     "user",
-			Password: "password",
-			DBName:   "procrastigo_db",
-			SSLMode:  "disable",
+			Host:        "localhost",
+			Port:        5432,
+			User:        "user",
+			Password:    "password",
+			DBName:      "procrastigo_db",
+			SSLMode:     "disable",
+			AutoMigrate: true,
+		},
+		Storage: storageCfg{
+			Driver:     "memory",
+			SeedFile:   "data/excuses.json",
+			SeedMode:   "strict",
+			SeedPolicy: "if_empty",
+			Persistence: persistenceCfg{
+				Fsync:            "always",
+				SnapshotInterval: 5 * time.Minute,
+			},
+			SQLite: sqliteCfg{
+				Path: "data/procrastigo.db",
+			},
+		},
+		Random: randomCfg{
+			DefaultMode:   "uniform",
+			WeightFloor:   0.5,
+			MaxCandidates: 1000,
+			HistorySize:   10,
+			MaxClients:    10000,
+		},
+		Daily: dailyCfg{
+			Timezone: "UTC",
+		},
+		Catalog: catalogCfg{
+			File: "configs/catalog.yaml",
+		},
+		Dedup: dedupCfg{
+			Threshold: 0.8,
+		},
+		Policy: policyCfg{
+			Links: "allow",
 		},
 	}
 
-	data, err := ioutil.ReadFile("configs/config.yaml")
-	if err != nil {
-		// if file not found, return defaults
-		return cfg
+	var problems problemList
+	if err := cfg.applyFile(path, &problems); err != nil {
+		return nil, err
 	}
+	cfg.applyEnv(&problems)
+	cfg.validate(&problems)
 
-	var fileCfg Config
-	if err := yaml.Unmarshal(data, &fileCfg); err != nil {
-		return cfg
+	if len(problems) > 0 {
+		return nil, &ValidationError{File: path, Problems: problems}
 	}
+	return cfg, nil
+}
 
-	if fileCfg.Server.Host != "" {
-		cfg.Server.Host = fileCfg.Server.Host
-	}
-	if fileCfg.Server.Port != 0 {
-		cfg.Server.Port = fileCfg.Server.Port
-	}
-	if fileCfg.Logging.Level != "" {
-		cfg.Logging.Level = fileCfg.Logging.Level
+// applyFile переопределяет значения теми ключами, которые заданы в YAML файле.
+// Ошибки чтения возвращаются сразу, ошибки содержимого копятся в problems.
+func (c *Config) applyFile(path string, problems *problemList) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && path == DefaultPath {
+			return nil
+		}
+		return fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	// ⚙️ Обновляем настройки БД, если они есть в файле
-	if fileCfg.Database.Host != "" {
-		cfg.Database.Host = fileCfg.Database.Host
-	}
-	if fileCfg.Database.Port != 0 {
-		cfg.Database.Port = fileCfg.Database.Port
-	}
-	if fileCfg.Database.User != "" {
-		cfg.Database.User = fileCfg.Database.User
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
 	}
-	if fileCfg.Database.Password != "" {
-		cfg.Database.Password = fileCfg.Database.Password
+	if len(doc.Content) == 0 {
+		// пустой файл
+		return nil
 	}
-	if fileCfg.Database.DBName != "" {
-		cfg.Database.DBName = fileCfg.Database.DBName
-	}
-	if fileCfg.Database.SSLMode != "" {
-		cfg.Database.SSLMode = fileCfg.Database.SSLMode
+
+	decodeNode(doc.Content[0], reflect.ValueOf(c).Elem(), "", problems)
+	return nil
+}
+
+// envBinding связывает переменную окружения с полем конфигурации.
+// Заполнено ровно одно из полей str, num, real, flag или dur.
+type envBinding struct {
+	name string
+	str  *string
+	num  *int
+	real *float64
+	flag *bool
+	dur  *time.Duration
+}
+
+// envBindings перечисляет все переменные окружения, которые переопределяют
+// значения из файла. Имена DATABASE_* совпадают с docker-compose.yaml.
+func (c *Config) envBindings() []envBinding {
+	return []envBinding{
+		{name: "PROCRASTIGO_SERVER_HOST", str: &c.Server.Host},
+		{name: "PROCRASTIGO_SERVER_PORT", num: &c.Server.Port},
+		{name: "PROCRASTIGO_STORAGE_TIMEOUT", dur: &c.Server.StorageTimeout},
+		{name: "PROCRASTIGO_PAGE_SIZE", num: &c.Server.PageSize},
+		{name: "PROCRASTIGO_MAX_PAGE_SIZE", num: &c.Server.MaxPageSize},
+		{name: "PROCRASTIGO_LOG_LEVEL", str: &c.Logging.Level},
+		{name: "DATABASE_HOST", str: &c.Database.Host},
+		{name: "DATABASE_PORT", num: &c.Database.Port},
+		{name: "DATABASE_USER", str: &c.Database.User},
+		{name: "DATABASE_PASSWORD", str: &c.Database.Password},
+		{name: "DATABASE_NAME", str: &c.Database.DBName},
+		{name: "DATABASE_SSLMODE", str: &c.Database.SSLMode},
+		{name: "DATABASE_AUTO_MIGRATE", flag: &c.Database.AutoMigrate},
+		{name: "PROCRASTIGO_STORAGE_DRIVER", str: &c.Storage.Driver},
+		{name: "PROCRASTIGO_STORAGE_SEED_FILE", str: &c.Storage.SeedFile},
+		{name: "PROCRASTIGO_STORAGE_SEED_MODE", str: &c.Storage.SeedMode},
+		{name: "PROCRASTIGO_STORAGE_SEED_POLICY", str: &c.Storage.SeedPolicy},
+		{name: "PROCRASTIGO_STORAGE_DIR", str: &c.Storage.Persistence.Dir},
+		{name: "PROCRASTIGO_STORAGE_FSYNC", str: &c.Storage.Persistence.Fsync},
+		{name: "PROCRASTIGO_STORAGE_SNAPSHOT_INTERVAL", dur: &c.Storage.Persistence.SnapshotInterval},
+		{name: "PROCRASTIGO_SQLITE_PATH", str: &c.Storage.SQLite.Path},
+		{name: "PROCRASTIGO_RANDOM_MODE", str: &c.Random.DefaultMode},
+		{name: "PROCRASTIGO_RANDOM_WEIGHT_FLOOR", real: &c.Random.WeightFloor},
+		{name: "PROCRASTIGO_RANDOM_MAX_CANDIDATES", num: &c.Random.MaxCandidates},
+		{name: "PROCRASTIGO_RANDOM_HISTORY_SIZE", num: &c.Random.HistorySize},
+		{name: "PROCRASTIGO_RANDOM_MAX_CLIENTS", num: &c.Random.MaxClients},
+		{name: "PROCRASTIGO_DAILY_TIMEZONE", str: &c.Daily.Timezone},
+		{name: "PROCRASTIGO_CATALOG_FILE", str: &c.Catalog.File},
+		{name: "PROCRASTIGO_DEDUP_THRESHOLD", real: &c.Dedup.Threshold},
+		{name: "PROCRASTIGO_ADMIN_TOKEN", str: &c.Admin.Token},
+		{name: "PROCRASTIGO_MODERATION_ENABLED", flag: &c.Moderation.Enabled},
+		{name: "PROCRASTIGO_POLICY_MIN_LENGTH", num: &c.Policy.MinLength},
+		{name: "PROCRASTIGO_POLICY_MAX_LENGTH", num: &c.Policy.MaxLength},
+		{name: "PROCRASTIGO_POLICY_LINKS", str: &c.Policy.Links},
+		{name: "PROCRASTIGO_VOTING_COOKIE_SECRET", str: &c.Voting.CookieSecret},
 	}
+}
 
-	return cfg
+// applyEnv переопределяет значения заданными переменными окружения.
+func (c *Config) applyEnv(problems *problemList) {
+	for _, b := range c.envBindings() {
+		value, ok := os.LookupEnv(b.name)
+		if !ok {
+			continue
+		}
+
+		if b.str != nil {
+			*b.str = value
+			continue
+		}
+
+		if b.dur != nil {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				problems.add(b.name, "must be a duration like 5s, got %q", value)
+				continue
+			}
+			*b.dur = d
+			continue
+		}
+
+		if b.real != nil {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				problems.add(b.name, "must be a number, got %q", value)
+				continue
+			}
+			*b.real = f
+			continue
+		}
+
+		if b.flag != nil {
+			v, err := strconv.ParseBool(value)
+			if err != nil {
+				problems.add(b.name, "must be a boolean, got %q", value)
+				continue
+			}
+			*b.flag = v
+			continue
+		}
+
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			problems.add(b.name, "must be an integer, got %q", value)
+			continue
+		}
+		*b.num = n
+	}
 }
 
 func (c *Config) LogLevel() string {
 	return c.Logging.Level
 }
 
+func (c *Config) StorageTimeout() time.Duration {
+	return c.Server.StorageTimeout
+}
+
+// DailyLocation возвращает часовой пояс "оправдания дня".
+// Значение уже проверено в Load.
+func (c *Config) DailyLocation() *time.Location {
+	loc, err := time.LoadLocation(c.Daily.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
 func (c *Config) ServerAddress() string {
 	return fmt.Sprintf("%s:%d", c.Server.Host, c.Server.Port)
 }
 
+func (c *Config) StorageDriver() string {
+	return c.Storage.Driver
+}
+
 func (c *Config) DatabaseDSN() string { // <--- НОВЫЙ МЕТОД
 	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		c.Database.Host, c.Database.Port, c.Database.User, c.Database.Password, c.Database.DBName, c.Database.SSLMode)
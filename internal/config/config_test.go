@@ -0,0 +1,231 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig записывает YAML во временный файл и возвращает путь к нему
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// problemPaths возвращает отсортированные пути проблем из ошибки Load
+func problemPaths(t *testing.T, err error) []string {
+	t.Helper()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("got %v, want *ValidationError", err)
+	}
+	var paths []string
+	for _, p := range verr.Problems {
+		paths = append(paths, p.Path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestLoadDefaults(t *testing.T) {
+	// файла по умолчанию нет рядом с тестом: берутся значения по умолчанию
+	cfg, err := Load(DefaultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.StorageDriver() != "memory" || cfg.ServerAddress() != "0.0.0.0:8080" || cfg.LogLevel() != "info" {
+		t.Errorf("unexpected defaults: %+v", cfg)
+	}
+	if cfg.StorageTimeout() != 5*time.Second || cfg.DailyLocation() != time.UTC {
+		t.Errorf("unexpected defaults: %+v", cfg)
+	}
+	if cfg.Policy.MinLength != 0 || cfg.Policy.MaxLength != 0 || cfg.Policy.Links != "allow" {
+		t.Errorf("content policy must be permissive by default: %+v", cfg.Policy)
+	}
+}
+
+func TestLoadShippedConfig(t *testing.T) {
+	if _, err := Load("../../configs/config.yaml"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "nope.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "failed to read config file") {
+		t.Errorf("got %v, want read error", err)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		problems []string
+		check    func(*Config) bool
+	}{
+		{
+			name:    "empty file",
+			content: "",
+			check:   func(c *Config) bool { return c.Server.Port == 8080 },
+		},
+		{
+			name:    "overrides keep other defaults",
+			content: "server:\n  port: 9090\nstorage:\n  driver: sqlite\n  sqlite:\n    path: /tmp/x.db\n",
+			check: func(c *Config) bool {
+				return c.Server.Port == 9090 && c.Server.Host == "0.0.0.0" &&
+					c.Storage.Driver == "sqlite" && c.Storage.SQLite.Path == "/tmp/x.db" && c.Storage.SeedPolicy == "if_empty"
+			},
+		},
+		{
+			name:    "durations and lists",
+			content: "server:\n  storage_timeout: 250ms\npolicy:\n  banned_words:\n    ru: [спам, реклам*]\n",
+			check: func(c *Config) bool {
+				return c.Server.StorageTimeout == 250*time.Millisecond && len(c.Policy.BannedWords["ru"]) == 2
+			},
+		},
+		{
+			name:     "unknown keys",
+			content:  "server:\n  prot: 1\nextra: true\n",
+			problems: []string{"extra", "server.prot"},
+		},
+		{
+			name:     "type errors",
+			content:  "server:\n  port: http\ndedup:\n  threshold: high\n",
+			problems: []string{"dedup.threshold", "server.port"},
+		},
+		{
+			name:     "section is not a mapping",
+			content:  "server: 8080\n",
+			problems: []string{"server"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load(writeConfig(t, tt.content))
+			if tt.problems != nil {
+				if got := problemPaths(t, err); strings.Join(got, ",") != strings.Join(tt.problems, ",") {
+					t.Errorf("problems %v, want %v", got, tt.problems)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(cfg) {
+				t.Errorf("unexpected config: %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		problems []string
+		check    func(*Config) bool
+	}{
+		{
+			name: "overrides file",
+			env: map[string]string{
+				"PROCRASTIGO_SERVER_PORT":        "7070",
+				"PROCRASTIGO_STORAGE_TIMEOUT":    "2s",
+				"PROCRASTIGO_DEDUP_THRESHOLD":    "0.5",
+				"PROCRASTIGO_MODERATION_ENABLED": "false",
+				"DATABASE_HOST":                  "db",
+			},
+			check: func(c *Config) bool {
+				return c.Server.Port == 7070 && c.Server.StorageTimeout == 2*time.Second &&
+					c.Dedup.Threshold == 0.5 && !c.Moderation.Enabled && c.Database.Host == "db"
+			},
+		},
+		{
+			name: "bad values",
+			env: map[string]string{
+				"PROCRASTIGO_SERVER_PORT":        "http",
+				"PROCRASTIGO_STORAGE_TIMEOUT":    "5",
+				"PROCRASTIGO_DEDUP_THRESHOLD":    "half",
+				"PROCRASTIGO_MODERATION_ENABLED": "maybe",
+			},
+			problems: []string{"PROCRASTIGO_DEDUP_THRESHOLD", "PROCRASTIGO_MODERATION_ENABLED", "PROCRASTIGO_SERVER_PORT", "PROCRASTIGO_STORAGE_TIMEOUT"},
+		},
+		{
+			name:     "valid type, invalid value",
+			env:      map[string]string{"PROCRASTIGO_STORAGE_DRIVER": "mongo"},
+			problems: []string{"storage.driver"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, "server:\n  port: 9090\nmoderation:\n  enabled: true\nadmin:\n  token: secret\n")
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			cfg, err := Load(path)
+			if tt.problems != nil {
+				if got := problemPaths(t, err); strings.Join(got, ",") != strings.Join(tt.problems, ",") {
+					t.Errorf("problems %v, want %v", got, tt.problems)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(cfg) {
+				t.Errorf("unexpected config: %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		problems []string
+	}{
+		{"unknown driver", "storage:\n  driver: mongo\n", []string{"storage.driver"}},
+		{"empty driver", "storage:\n  driver: \"\"\n", []string{"storage.driver"}},
+		{"sqlite without path", "storage:\n  driver: sqlite\n  sqlite:\n    path: \"\"\n", []string{"storage.sqlite.path"}},
+		{"ports", "server:\n  port: 0\ndatabase:\n  port: 70000\n", []string{"database.port", "server.port"}},
+		{"page size above max", "server:\n  page_size: 200\n", []string{"server.page_size"}},
+		{"enums", "logging:\n  level: loud\nrandom:\n  default_mode: chaos\npolicy:\n  links: maybe\n", []string{"logging.level", "policy.links", "random.default_mode"}},
+		{"time zone", "daily:\n  timezone: Mars/Olympus\n", []string{"daily.timezone"}},
+		{"moderation without token", "moderation:\n  enabled: true\n", []string{"moderation.enabled"}},
+		{"policy lengths", "policy:\n  min_length: 20\n  max_length: 10\n", []string{"policy.max_length"}},
+		{"banned word", "policy:\n  banned_words:\n    en: [\"two words\"]\n", []string{"policy.banned_words.en"}},
+		{"short cookie secret", "voting:\n  cookie_secret: short\n", []string{"voting.cookie_secret"}},
+		{"empty api key", "voting:\n  api_keys: [\"\"]\n", []string{"voting.api_keys[0]"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, tt.content))
+			if got := problemPaths(t, err); strings.Join(got, ",") != strings.Join(tt.problems, ",") {
+				t.Errorf("problems %v, want %v", got, tt.problems)
+			}
+		})
+	}
+}
+
+func TestValidationErrorListsEveryProblem(t *testing.T) {
+	path := writeConfig(t, "server:\n  port: 0\nstorage:\n  driver: mongo\n")
+	_, err := Load(path)
+	msg := err.Error()
+	for _, want := range []string{path, "2 problem(s)", "server.port", `storage.driver: must be one of [memory, postgres, sqlite], got "mongo"`} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error %q does not mention %q", msg, want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Допустимые значения перечислимых полей
+var (
+	validLogLevels    = []string{"debug", "info", "warn", "error", "production"}
+	validDrivers      = []string{"memory", "postgres", "sqlite"}
+	validSSLModes     = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	validRandModes    = []string{"uniform", "weighted", "fresh"}
+	validSeedModes    = []string{"strict", "lenient"}
+	validSeedPolicies = []string{"if_empty", "always", "never"}
+	validFsyncModes   = []string{"always", "interval", "never"}
+	validLinksModes   = []string{"allow", "strip", "reject"}
+)
+
+// Problem описывает одну ошибку конфигурации. Path - путь в YAML
+// (например, server.port) или имя переменной окружения.
+type Problem struct {
+	Path    string
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.Path, p.Message)
+}
+
+// ValidationError содержит все проблемы, найденные при загрузке конфигурации.
+type ValidationError struct {
+	File     string
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid configuration (%s): %d problem(s)", e.File, len(e.Problems))
+	for _, p := range e.Problems {
+		b.WriteString("\n  - ")
+		b.WriteString(p.String())
+	}
+	return b.String()
+}
+
+type problemList []Problem
+
+func (l *problemList) add(path, format string, args ...interface{}) {
+	*l = append(*l, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// decodeNode раскладывает YAML узел в v. Для структур ключи сверяются с
+// yaml тегами полей, поэтому неизвестные ключи и ошибки типов попадают
+// в problems вместе с полным путем, а не теряются.
+func decodeNode(node *yaml.Node, v reflect.Value, path string, problems *problemList) {
+	if v.Kind() != reflect.Struct {
+		if err := node.Decode(v.Addr().Interface()); err != nil {
+			problems.add(path, "expected %s, got %q (line %d)", v.Type(), node.Value, node.Line)
+		}
+		return
+	}
+
+	if node.Kind != yaml.MappingNode {
+		problems.add(pathOrRoot(path), "expected a mapping (line %d)", node.Line)
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		keyPath := joinPath(path, key.Value)
+
+		field, ok := fieldByTag(v, key.Value)
+		if !ok {
+			problems.add(keyPath, "unknown key (line %d)", key.Line)
+			continue
+		}
+		decodeNode(value, field, keyPath, problems)
+	}
+}
+
+// fieldByTag ищет поле структуры по имени из yaml тега.
+func fieldByTag(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if tag == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func joinPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "<root>"
+	}
+	return path
+}
+
+// validate проверяет значения после применения файла и окружения.
+func (c *Config) validate(problems *problemList) {
+	checkPort(problems, "server.port", c.Server.Port)
+	checkPort(problems, "database.port", c.Database.Port)
+	if c.Server.StorageTimeout <= 0 {
+		problems.add("server.storage_timeout", "must be positive, got %s", c.Server.StorageTimeout)
+	}
+	if c.Server.MaxPageSize < 1 {
+		problems.add("server.max_page_size", "must be positive, got %d", c.Server.MaxPageSize)
+	}
+	if c.Server.PageSize < 1 || c.Server.PageSize > c.Server.MaxPageSize {
+		problems.add("server.page_size", "must be between 1 and server.max_page_size (%d), got %d", c.Server.MaxPageSize, c.Server.PageSize)
+	}
+	checkOneOf(problems, "logging.level", c.Logging.Level, validLogLevels)
+	checkOneOf(problems, "database.sslmode", c.Database.SSLMode, validSSLModes)
+
+	checkOneOf(problems, "storage.driver", c.Storage.Driver, validDrivers)
+	checkOneOf(problems, "storage.seed_mode", c.Storage.SeedMode, validSeedModes)
+	checkOneOf(problems, "storage.seed_policy", c.Storage.SeedPolicy, validSeedPolicies)
+	checkOneOf(problems, "storage.persistence.fsync", c.Storage.Persistence.Fsync, validFsyncModes)
+	if c.Storage.Persistence.SnapshotInterval < 0 {
+		problems.add("storage.persistence.snapshot_interval", "must not be negative, got %s", c.Storage.Persistence.SnapshotInterval)
+	}
+	if c.Storage.Driver == "sqlite" && c.Storage.SQLite.Path == "" {
+		problems.add("storage.sqlite.path", "must not be empty for driver sqlite")
+	}
+
+	checkOneOf(problems, "random.default_mode", c.Random.DefaultMode, validRandModes)
+	if c.Random.WeightFloor <= 0 {
+		problems.add("random.weight_floor", "must be positive, got %g", c.Random.WeightFloor)
+	}
+	if c.Random.MaxCandidates < 1 {
+		problems.add("random.max_candidates", "must be positive, got %d", c.Random.MaxCandidates)
+	}
+	if c.Random.HistorySize < 0 {
+		problems.add("random.history_size", "must not be negative, got %d", c.Random.HistorySize)
+	}
+	if c.Random.MaxClients < 1 {
+		problems.add("random.max_clients", "must be positive, got %d", c.Random.MaxClients)
+	}
+
+	if _, err := time.LoadLocation(c.Daily.Timezone); err != nil || c.Daily.Timezone == "" {
+		problems.add("daily.timezone", "unknown time zone %q", c.Daily.Timezone)
+	}
+
+	if c.Catalog.File == "" {
+		problems.add("catalog.file", "must not be empty")
+	}
+
+	if c.Dedup.Threshold < 0 || c.Dedup.Threshold > 1 {
+		problems.add("dedup.threshold", "must be between 0 and 1, got %g", c.Dedup.Threshold)
+	}
+	if c.Moderation.Enabled && c.Admin.Token == "" {
+		problems.add("moderation.enabled", "requires admin.token: the moderation queue is part of the admin API")
+	}
+
+	if c.Policy.MinLength < 0 {
+		problems.add("policy.min_length", "must not be negative, got %d", c.Policy.MinLength)
+	}
+	if c.Policy.MaxLength < 0 || (c.Policy.MaxLength > 0 && c.Policy.MaxLength < c.Policy.MinLength) {
+		problems.add("policy.max_length", "must be 0 or at least policy.min_length (%d), got %d", c.Policy.MinLength, c.Policy.MaxLength)
+	}
+	checkOneOf(problems, "policy.links", c.Policy.Links, validLinksModes)
+	for language, words := range c.Policy.BannedWords {
+		for _, word := range words {
+			if !validBannedWord(word) {
+				problems.add("policy.banned_words."+language, "must be letters and digits with an optional trailing *, got %q", word)
+			}
+		}
+	}
+
+	for i, key := range c.Voting.APIKeys {
+		if strings.TrimSpace(key) == "" {
+			problems.add(fmt.Sprintf("voting.api_keys[%d]", i), "must not be empty")
+		}
+	}
+	if c.Voting.CookieSecret != "" && len(c.Voting.CookieSecret) < minCookieSecret {
+		problems.add("voting.cookie_secret", "must be at least %d characters long", minCookieSecret)
+	}
+}
+
+// minCookieSecret - минимальная длина voting.cookie_secret
+const minCookieSecret = 16
+
+// validBannedWord проверяет запись списка запрещенных слов: буквы и цифры,
+// в конце может быть * (все слова с этим началом).
+func validBannedWord(word string) bool {
+	word = strings.TrimSuffix(word, "*")
+	if word == "" {
+		return false
+	}
+	for _, r := range word {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func checkPort(problems *problemList, path string, port int) {
+	if port < 1 || port > 65535 {
+		problems.add(path, "must be between 1 and 65535, got %d", port)
+	}
+}
+
+func checkOneOf(problems *problemList, path, value string, allowed []string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	problems.add(path, "must be one of [%s], got %q", strings.Join(allowed, ", "), value)
+}
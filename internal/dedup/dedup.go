@@ -0,0 +1,110 @@
+// Package dedup находит почти одинаковые оправдания. Сходство текстов -
+// доля общих триграмм, посчитанная так же, как similarity() из pg_trgm,
+// поэтому порог одинаково работает во всех бэкендах: регистр, пунктуация
+// и порядок слов на него почти не влияют.
+package dedup
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Trigrams - множество триграмм текста
+type Trigrams map[string]struct{}
+
+// NewTrigrams разбивает text на слова из букв и цифр и собирает триграммы
+// каждого слова, дополненного как в pg_trgm: два пробела в начале и один
+// в конце.
+func NewTrigrams(text string) Trigrams {
+	set := make(Trigrams)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = struct{}{}
+		}
+	}
+	return set
+}
+
+// Similarity возвращает сходство от 0 (ничего общего) до 1 (одинаковые
+// множества триграмм).
+func Similarity(a, b Trigrams) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	common := 0
+	for trigram := range a {
+		if _, ok := b[trigram]; ok {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// Pair - два похожих документа
+type Pair struct {
+	A, B       string
+	Similarity float64
+}
+
+// Group - документы, связанные цепочкой похожих пар, и наибольшее
+// сходство пары внутри группы
+type Group struct {
+	IDs        []string
+	Similarity float64
+}
+
+// Groups объединяет пары в группы (компоненты связности). id в группе
+// отсортированы; группы - по убыванию размера, затем по первому id.
+func Groups(pairs []Pair) []Group {
+	parent := make(map[string]string)
+	var find func(id string) string
+	find = func(id string) string {
+		p, ok := parent[id]
+		if !ok {
+			parent[id] = id
+			return id
+		}
+		if p != id {
+			parent[id] = find(p)
+		}
+		return parent[id]
+	}
+	for _, pair := range pairs {
+		parent[find(pair.A)] = find(pair.B)
+	}
+
+	byRoot := make(map[string]*Group)
+	for id := range parent {
+		root := find(id)
+		if byRoot[root] == nil {
+			byRoot[root] = &Group{}
+		}
+		byRoot[root].IDs = append(byRoot[root].IDs, id)
+	}
+	for _, pair := range pairs {
+		if g := byRoot[find(pair.A)]; pair.Similarity > g.Similarity {
+			g.Similarity = pair.Similarity
+		}
+	}
+
+	groups := make([]Group, 0, len(byRoot))
+	for _, g := range byRoot {
+		sort.Strings(g.IDs)
+		groups = append(groups, *g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if len(groups[i].IDs) != len(groups[j].IDs) {
+			return len(groups[i].IDs) > len(groups[j].IDs)
+		}
+		return groups[i].IDs[0] < groups[j].IDs[0]
+	})
+	return groups
+}
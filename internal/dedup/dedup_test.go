@@ -0,0 +1,46 @@
+package dedup
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	sim := func(a, b string) float64 { return Similarity(NewTrigrams(a), NewTrigrams(b)) }
+
+	if got := sim("Мой кот сел на клавиатуру!", "мой кот, сел на клавиатуру"); got != 1 {
+		t.Errorf("punctuation and case: got %g, want 1", got)
+	}
+	if got := sim("word", "two words"); math.Abs(got-4.0/11) > 1e-9 {
+		// как SELECT similarity('word', 'two words') в pg_trgm
+		t.Errorf("pg_trgm example: got %g, want %g", got, 4.0/11)
+	}
+	if got := sim("cat", "dog"); got != 0 {
+		t.Errorf("unrelated: got %g", got)
+	}
+	if got := sim("", "dog"); got != 0 {
+		t.Errorf("empty: got %g", got)
+	}
+	if a, b := sim("Мой кот сел на клавиатуру", "Мой кот сел на клавиатуру и удалил код"), sim("Мой кот сел на клавиатуру", "Собака съела ноутбук"); a <= b || a < 0.5 {
+		t.Errorf("near duplicate %g must be well above unrelated %g", a, b)
+	}
+}
+
+func TestGroups(t *testing.T) {
+	groups := Groups([]Pair{
+		{"c", "d", 0.9},
+		{"a", "b", 0.7},
+		{"b", "e", 0.8},
+	})
+	want := []Group{
+		{IDs: []string{"a", "b", "e"}, Similarity: 0.8},
+		{IDs: []string{"c", "d"}, Similarity: 0.9},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("got %+v, want %+v", groups, want)
+	}
+	if groups := Groups(nil); len(groups) != 0 {
+		t.Errorf("no pairs: got %+v", groups)
+	}
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"procrastigo/internal/config"
+	"procrastigo/internal/models"
+	"procrastigo/internal/storage"
+	"procrastigo/pkg/logger"
+	"procrastigo/pkg/utils"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultClusterThreshold - порог для поиска дубликатов, если проверка
+// при создании выключена (dedup.threshold: 0)
+const defaultClusterThreshold = 0.8
+
+// maxRejectionReason - предельная длина причины отказа в символах
+const maxRejectionReason = 500
+
+// AdminHandler - служебные методы для модераторов (/api/v1/admin).
+// Доступ проверяет AdminMiddleware.
+type AdminHandler struct {
+	storage     storage.Storage
+	threshold   float64
+	pageSize    int
+	maxPageSize int
+}
+
+func NewAdminHandler(storage storage.Storage, cfg *config.Config) *AdminHandler {
+	threshold := cfg.Dedup.Threshold
+	if threshold == 0 {
+		threshold = defaultClusterThreshold
+	}
+	return &AdminHandler{
+		storage:     storage,
+		threshold:   threshold,
+		pageSize:    cfg.Server.PageSize,
+		maxPageSize: cfg.Server.MaxPageSize,
+	}
+}
+
+// GetDuplicates отдает группы почти одинаковых оправданий. Параметр
+// threshold (0..1] переопределяет dedup.threshold.
+func (h *AdminHandler) GetDuplicates(w http.ResponseWriter, r *http.Request) {
+	threshold := h.threshold
+	if value := r.URL.Query().Get("threshold"); value != "" {
+		t, err := strconv.ParseFloat(value, 64)
+		if err != nil || t <= 0 || t > 1 {
+			utils.ErrorResponse(w, http.StatusBadRequest, "Invalid threshold")
+			return
+		}
+		threshold = t
+	}
+
+	clusters, err := h.storage.DuplicateClusters(r.Context(), threshold)
+	if err != nil {
+		storageErrorResponse(w, err, "Failed to find duplicates")
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, clusters)
+}
+
+// GetModerationQueue отдает страницу оправданий со статусом status
+// (по умолчанию pending), самые старые первыми. Параметры limit и cursor -
+// как у списка оправданий.
+func (h *AdminHandler) GetModerationQueue(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	status := strings.ToLower(query.Get("status"))
+	if status == "" {
+		status = models.StatusPending
+	}
+	if !storage.ValidStatus(status) {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid status")
+		return
+	}
+
+	page := storage.PageRequest{
+		Sort:   storage.SortCreatedAt,
+		Limit:  utils.ParseLimit(query.Get("limit"), h.pageSize),
+		Cursor: query.Get("cursor"),
+	}
+	if page.Limit == 0 {
+		page.Limit = h.pageSize
+	}
+	if page.Limit > h.maxPageSize {
+		page.Limit = h.maxPageSize
+	}
+
+	result, err := h.storage.GetExcuses(r.Context(), storage.ExcuseFilter{Status: status}, page)
+	if err != nil {
+		storageErrorResponse(w, err, "Failed to get moderation queue")
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, result)
+}
+
+// ApproveExcuse публикует оправдание из очереди модерации.
+func (h *AdminHandler) ApproveExcuse(w http.ResponseWriter, r *http.Request) {
+	h.moderate(w, r, models.StatusApproved, "")
+}
+
+// RejectExcuse отклоняет оправдание из очереди; причина обязательна.
+func (h *AdminHandler) RejectExcuse(w http.ResponseWriter, r *http.Request) {
+	var req models.RejectRequest
+	if err := utils.JSONDecode(r.Body, &req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Reason is required")
+		return
+	}
+	if utf8.RuneCountInString(reason) > maxRejectionReason {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Reason is too long")
+		return
+	}
+
+	h.moderate(w, r, models.StatusRejected, reason)
+}
+
+func (h *AdminHandler) moderate(w http.ResponseWriter, r *http.Request, status, reason string) {
+	excuse, err := h.storage.ModerateExcuse(r.Context(), mux.Vars(r)["id"], status, reason)
+	if errors.Is(err, storage.ErrConflict) {
+		utils.ErrorResponse(w, http.StatusConflict, "Excuse is not pending moderation")
+		return
+	}
+	if err != nil {
+		storageErrorResponse(w, err, "Failed to moderate excuse")
+		return
+	}
+
+	logger.LogExcuseRequest(excuse, strings.ToUpper(status))
+	utils.JSONResponse(w, http.StatusOK, excuse)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"procrastigo/internal/catalog"
+	"procrastigo/pkg/utils"
+	"strings"
+)
+
+// CatalogHandler отдает справочники категорий, языков и уровней серьезности.
+type CatalogHandler struct {
+	catalog *catalog.Catalog
+}
+
+func NewCatalogHandler(cat *catalog.Catalog) *CatalogHandler {
+	return &CatalogHandler{catalog: cat}
+}
+
+func (h *CatalogHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, catalog.KindCategory)
+}
+
+func (h *CatalogHandler) GetLanguages(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, catalog.KindLanguage)
+}
+
+func (h *CatalogHandler) GetSeverities(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, catalog.KindSeverity)
+}
+
+// list отдает справочник kind. Язык названий берется из параметра lang,
+// затем из Accept-Language; без них используется display_language каталога.
+func (h *CatalogHandler) list(w http.ResponseWriter, r *http.Request, kind string) {
+	lang := r.URL.Query().Get("lang")
+	if lang == "" {
+		lang = acceptLanguage(r)
+	}
+	utils.JSONResponse(w, http.StatusOK, h.catalog.Items(kind, lang))
+}
+
+// acceptLanguage возвращает основной язык первого значения Accept-Language
+// ("en-US,en;q=0.9" -> "en").
+func acceptLanguage(r *http.Request) string {
+	value := r.Header.Get("Accept-Language")
+	if value == "" {
+		return ""
+	}
+	first := strings.TrimSpace(strings.Split(value, ",")[0])
+	first = strings.Split(first, ";")[0]
+	return strings.ToLower(strings.Split(first, "-")[0])
+}
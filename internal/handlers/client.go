@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const (
+	clientIDHeader   = "X-Client-ID"
+	clientCookieName = "procrastigo_client"
+	clientCookieAge  = 365 * 24 * 60 * 60
+)
+
+// clientID возвращает идентификатор клиента для истории показов:
+// заголовок X-Client-ID или cookie procrastigo_client. Если нет ни того,
+// ни другого, клиенту выдается новая cookie.
+func clientID(w http.ResponseWriter, r *http.Request) string {
+	if id := r.Header.Get(clientIDHeader); id != "" {
+		return "header:" + shortHash(id)
+	}
+
+	if cookie, err := r.Cookie(clientCookieName); err == nil && cookie.Value != "" {
+		return "cookie:" + shortHash(cookie.Value)
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	value := hex.EncodeToString(buf)
+	http.SetCookie(w, &http.Cookie{
+		Name:     clientCookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   clientCookieAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return "cookie:" + shortHash(value)
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"procrastigo/internal/storage"
+	"procrastigo/pkg/logger"
+	"procrastigo/pkg/utils"
+)
+
+// storageErrorResponse - единственное место, где ошибки хранилища
+// превращаются в HTTP статусы:
+//
+//	storage.ErrNotFound       -> 404
+//	storage.ErrConflict       -> 409
+//	storage.ErrInvalid        -> 400 (клиенту - только сообщение InvalidError)
+//	storage.ErrUnavailable    -> 503
+//	context.DeadlineExceeded  -> 504
+//	context.Canceled          -> ответа нет, клиент уже отключился
+//
+// Остальные ошибки логируются и отдаются как 500 с текстом message.
+func storageErrorResponse(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		utils.ErrorResponse(w, http.StatusNotFound, "Excuse not found")
+	case errors.Is(err, storage.ErrConflict):
+		utils.ErrorResponse(w, http.StatusConflict, "Excuse already exists")
+	case errors.Is(err, storage.ErrInvalid):
+		var invalid *storage.InvalidError
+		if !errors.As(err, &invalid) {
+			// например, ограничение базы: текст драйвера остается в логе
+			logger.Warn.Printf("%s: %v", message, err)
+			utils.ErrorResponse(w, http.StatusBadRequest, "Invalid excuse data")
+			return
+		}
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid argument: "+invalid.Message)
+	case errors.Is(err, storage.ErrUnavailable):
+		logger.Error.Printf("%s: %v", message, err)
+		utils.ErrorResponse(w, http.StatusServiceUnavailable, "Storage unavailable")
+	case errors.Is(err, context.DeadlineExceeded):
+		logger.Warn.Printf("%s: %v", message, err)
+		utils.ErrorResponse(w, http.StatusGatewayTimeout, "Storage timeout")
+	case errors.Is(err, context.Canceled):
+		// клиент отключился, ответ уже никто не прочитает
+	default:
+		logger.Error.Printf("%s: %v", message, err)
+		utils.ErrorResponse(w, http.StatusInternalServerError, message)
+	}
+}
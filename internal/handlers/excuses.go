@@ -1,119 +1,236 @@
 package handlers
 
 import (
-	"database/sql" // Добавим для обработки sql.ErrNoRows
+	"errors"
+	"fmt"
 	"net/http"
+	"procrastigo/internal/catalog"
+	"procrastigo/internal/config"
 	"procrastigo/internal/models"
+	"procrastigo/internal/policy"
+	"procrastigo/internal/selection"
 	"procrastigo/internal/storage"
 	"procrastigo/pkg/logger"
 	"procrastigo/pkg/utils"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux" // Добавим для RateExcuse
 )
 
 type ExcuseHandler struct {
-	storage storage.Storage
+	storage     storage.Storage
+	catalog     *catalog.Catalog
+	selector    *selection.Selector
+	daily       *selection.DailyCache
+	randomMode  string
+	dailyLoc    *time.Location
+	pageSize    int
+	maxPageSize int
+	dedup       float64 // порог сходства для CreateExcuse; 0 - без проверки
+	moderation  bool    // новые оправдания ждут одобрения в статусе pending
+	content     *policy.Pipeline
+	voters      *voters
 }
 
-func NewExcuseHandler(storage storage.Storage) *ExcuseHandler {
-	return &ExcuseHandler{storage: storage}
+func NewExcuseHandler(storage storage.Storage, cat *catalog.Catalog, cfg *config.Config) *ExcuseHandler {
+	return &ExcuseHandler{
+		storage: storage,
+		catalog: cat,
+		selector: selection.New(storage, selection.Options{
+			WeightFloor:   cfg.Random.WeightFloor,
+			MaxCandidates: cfg.Random.MaxCandidates,
+			HistorySize:   cfg.Random.HistorySize,
+			MaxClients:    cfg.Random.MaxClients,
+		}),
+		daily:       selection.NewDailyCache(storage),
+		randomMode:  cfg.Random.DefaultMode,
+		dailyLoc:    cfg.DailyLocation(),
+		pageSize:    cfg.Server.PageSize,
+		maxPageSize: cfg.Server.MaxPageSize,
+		dedup:       cfg.Dedup.Threshold,
+		moderation:  cfg.Moderation.Enabled,
+		content:     newContentPolicy(cfg),
+		voters:      newVoters(cfg),
+	}
 }
 
-func (h *ExcuseHandler) GetRandomExcuse(w http.ResponseWriter, r *http.Request) {
-	lang := r.URL.Query().Get("lang")
-	category := r.URL.Query().Get("category")
+// newContentPolicy собирает проверку текста из настроек policy. Ссылки
+// обрабатываются первыми, чтобы длина считалась по тексту без них.
+func newContentPolicy(cfg *config.Config) *policy.Pipeline {
+	return policy.New(
+		policy.Links{Mode: cfg.Policy.Links},
+		policy.NewBannedWords(cfg.Policy.BannedWords),
+		policy.Length{Min: cfg.Policy.MinLength, Max: cfg.Policy.MaxLength},
+	)
+}
 
-	if lang != "" && !utils.ValidateLanguage(lang) {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid language")
+// GetRandomExcuse отдает случайное оправдание, подходящее под те же
+// фильтры, что и список (см. parseExcuseFilter). Параметр mode выбирает
+// стратегию: uniform, weighted или fresh (без недавно показанных клиенту).
+func (h *ExcuseHandler) GetRandomExcuse(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseExcuseFilter(r, h.catalog)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if category != "" && !utils.ValidateCategory(category) {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid category")
-		return
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = h.randomMode
 	}
-
-	excuse, err := h.storage.GetRandomExcuse()
-	if err != nil {
-		logger.Error.Printf("Failed to get random excuse: %v", err)
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+	if !h.selector.ValidMode(mode) {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid mode")
 		return
 	}
 
-	if excuse == nil {
+	excuse, err := h.selector.Select(r.Context(), mode, filter, clientID(w, r))
+	if errors.Is(err, storage.ErrNotFound) {
 		utils.ErrorResponse(w, http.StatusNotFound, "No excuses found")
 		return
 	}
+	if err != nil {
+		storageErrorResponse(w, err, "Failed to get random excuse")
+		return
+	}
 
 	logger.LogExcuseRequest(excuse, "RANDOM")
 	utils.JSONResponse(w, http.StatusOK, excuse)
 }
 
-func (h *ExcuseHandler) GetExcuses(w http.ResponseWriter, r *http.Request) {
-	category := r.URL.Query().Get("category")
-	lang := r.URL.Query().Get("lang")
-	severity := r.URL.Query().Get("severity")
-	limit := utils.ParseLimit(r.URL.Query().Get("limit"), 1)
-
-	if category != "" && !utils.ValidateCategory(category) {
+// GetDailyExcuse отдает "оправдание дня": одно и то же для всех клиентов
+// в течение календарного дня в поясе daily.timezone для заданных lang
+// (обязателен) и category. Ответ кешируется клиентами до ближайшей местной
+// полуночи, победитель запоминается в h.daily.
+func (h *ExcuseHandler) GetDailyExcuse(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	language := strings.ToLower(query.Get("lang"))
+	category := strings.ToLower(query.Get("category"))
+	if language == "" {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Missing lang")
+		return
+	}
+	if !h.catalog.ValidLanguage(language) {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid language")
+		return
+	}
+	if category != "" && !h.catalog.ValidCategory(category) {
 		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid category")
 		return
 	}
 
-	if lang != "" && !utils.ValidateLanguage(lang) {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid language")
+	now := time.Now()
+	date := utils.StartOfDayIn(now, h.dailyLoc).Format("2006-01-02")
+
+	excuse, err := h.daily.Get(r.Context(), language, category, date)
+	if errors.Is(err, storage.ErrNotFound) {
+		utils.ErrorResponse(w, http.StatusNotFound, "No excuses found")
+		return
+	}
+	if err != nil {
+		storageErrorResponse(w, err, "Failed to get daily excuse")
 		return
 	}
 
-	if severity != "" && !utils.ValidateSeverity(severity) {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid severity")
+	expires := utils.NextMidnightIn(now, h.dailyLoc)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(expires.Sub(now).Seconds())))
+	w.Header().Set("Expires", expires.UTC().Format(http.TimeFormat))
+
+	logger.LogExcuseRequest(excuse, "DAILY")
+	utils.JSONResponse(w, http.StatusOK, models.DailyExcuse{
+		Date:     date,
+		Timezone: h.dailyLoc.String(),
+		Excuse:   *excuse,
+	})
+}
+
+// GetExcuses отдает страницу оправданий. Параметры: фильтры (см. parseExcuseFilter),
+// sort, limit (не больше maxPageSize) и cursor из next_cursor предыдущей страницы.
+func (h *ExcuseHandler) GetExcuses(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page := storage.PageRequest{
+		Sort:   query.Get("sort"),
+		Limit:  utils.ParseLimit(query.Get("limit"), h.pageSize),
+		Cursor: query.Get("cursor"),
+	}
+	if page.Limit == 0 {
+		page.Limit = h.pageSize
+	}
+	if page.Limit > h.maxPageSize {
+		page.Limit = h.maxPageSize
+	}
+	if page.Sort != "" && !storage.ValidSort(page.Sort) {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid sort")
+		return
+	}
+
+	filter, err := parseExcuseFilter(r, h.catalog)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// В PostgreStorage фильтрация по severity пока не реализована,
-	// но мы передаем параметры, чтобы не ломать сигнатуру
-	excuses, err := h.storage.GetExcuses(category, lang, limit)
+	result, err := h.storage.GetExcuses(r.Context(), filter, page)
 	if err != nil {
-		logger.Error.Printf("Failed to get excuses: %v", err)
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+		storageErrorResponse(w, err, "Failed to get excuses")
 		return
 	}
 
-	utils.JSONResponse(w, http.StatusOK, excuses)
+	utils.JSONResponse(w, http.StatusOK, result)
 }
 
-func (h *ExcuseHandler) CreateExcuse(w http.ResponseWriter, r *http.Request) {
-	var req models.ExcuseRequest
-	if err := utils.JSONDecode(r.Body, &req); err != nil {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+// SearchExcuses ищет оправдания по словам из q (с учетом словоформ) и
+// отдает до limit результатов по убыванию релевантности с подсветкой.
+// Фильтры - те же, что у списка.
+func (h *ExcuseHandler) SearchExcuses(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	text := strings.TrimSpace(query.Get("q"))
+	if text == "" {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Missing q")
 		return
 	}
 
-	if req.Text == "" {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Text is required")
+	filter, err := parseExcuseFilter(r, h.catalog)
+	if err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if req.Category == "" {
-		req.Category = "general"
+	limit := utils.ParseLimit(query.Get("limit"), h.pageSize)
+	if limit == 0 {
+		limit = h.pageSize
 	}
-	if req.Language == "" {
-		req.Language = "ru"
+	if limit > h.maxPageSize {
+		limit = h.maxPageSize
 	}
-	if req.Severity == "" {
-		req.Severity = "medium"
+
+	hits, err := h.storage.SearchExcuses(r.Context(), storage.SearchQuery{Text: text, Filter: filter, Limit: limit})
+	if err != nil {
+		storageErrorResponse(w, err, "Failed to search excuses")
+		return
 	}
 
-	if !utils.ValidateCategory(req.Category) {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid category")
+	utils.JSONResponse(w, http.StatusOK, models.SearchResult{Query: text, Results: hits})
+}
+
+func (h *ExcuseHandler) CreateExcuse(w http.ResponseWriter, r *http.Request) {
+	var req models.ExcuseRequest
+	if err := utils.JSONDecode(r.Body, &req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
-	if !utils.ValidateLanguage(req.Language) {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid language")
+
+	if err := prepareExcuseRequest(&req, h.catalog); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if !utils.ValidateSeverity(req.Severity) {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid severity")
+	if !h.checkContent(w, &req) {
+		return
+	}
+
+	if !h.checkDuplicate(w, r, req.Text, "") {
 		return
 	}
 
@@ -123,45 +240,285 @@ func (h *ExcuseHandler) CreateExcuse(w http.ResponseWriter, r *http.Request) {
 		Category:  req.Category,
 		Language:  req.Language,
 		Severity:  req.Severity,
+		Tags:      req.Tags,
 		CreatedAt: utils.GetStartOfDay(),
 		Rating:    0, // <--- Инициализация Rating
+		Status:    models.StatusApproved,
+	}
+	status := http.StatusCreated
+	if h.moderation {
+		// оправдание появится в выдаче после одобрения модератором
+		excuse.Status = models.StatusPending
+		status = http.StatusAccepted
 	}
 
-	if err := h.storage.CreateExcuse(excuse); err != nil {
-		logger.Error.Printf("Failed to create excuse: %v", err)
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to create excuse")
+	if err := h.storage.CreateExcuse(r.Context(), excuse); err != nil {
+		storageErrorResponse(w, err, "Failed to create excuse")
 		return
 	}
 
 	logger.LogExcuseRequest(&excuse, "CREATE")
-	utils.JSONResponse(w, http.StatusCreated, excuse)
+	utils.JSONResponse(w, status, excuse)
+}
+
+// checkDuplicate отвечает 409, если у другого опубликованного оправдания
+// (не self) сходство текста не ниже порога dedup. Пустой self - оправдание
+// создается.
+func (h *ExcuseHandler) checkDuplicate(w http.ResponseWriter, r *http.Request, text, self string) bool {
+	if h.dedup == 0 {
+		return true
+	}
+
+	// при изменении среди похожих может оказаться старый текст самого
+	// оправдания, поэтому берем два
+	similar, err := h.storage.FindSimilar(r.Context(), text, h.dedup, 2)
+	if err != nil {
+		storageErrorResponse(w, err, "Failed to check for duplicates")
+		return false
+	}
+	for _, s := range similar {
+		if s.Excuse.ID == self {
+			continue
+		}
+		utils.JSONResponse(w, http.StatusConflict, duplicateResponse{
+			Error:   "Similar excuse already exists",
+			Similar: s,
+		})
+		return false
+	}
+	return true
+}
+
+// checkContent прогоняет текст через политику содержимого и при нарушениях
+// отвечает 400 со списком нарушений. Текст в req может быть исправлен
+// (например, без ссылок).
+func (h *ExcuseHandler) checkContent(w http.ResponseWriter, req *models.ExcuseRequest) bool {
+	content := policy.Content{Text: req.Text, Language: req.Language}
+	if violations := h.content.Check(&content); len(violations) > 0 {
+		utils.JSONResponse(w, http.StatusBadRequest, policyResponse{
+			Error:      "Excuse violates content policy",
+			Violations: violations,
+		})
+		return false
+	}
+	req.Text = content.Text
+	return true
+}
+
+// policyResponse - ответ 400 на текст, нарушающий политику содержимого
+type policyResponse struct {
+	Error      string             `json:"error"`
+	Violations []policy.Violation `json:"violations"`
+}
+
+// duplicateResponse - ответ 409 на почти одинаковое оправдание
+type duplicateResponse struct {
+	Error   string               `json:"error"`
+	Similar models.SimilarExcuse `json:"similar"`
+}
+
+// GetExcuse отдает одно оправдание по id. Оправдания на модерации и
+// отклоненные не показываются.
+func (h *ExcuseHandler) GetExcuse(w http.ResponseWriter, r *http.Request) {
+	excuse, ok := h.published(w, r, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusOK, excuse)
+}
+
+// published читает оправдание для публичных методов по id: неодобренные
+// отвечают 404, как несуществующие. PUT, PATCH и DELETE доступны только
+// модераторам и работают с оправданиями в любом статусе.
+func (h *ExcuseHandler) published(w http.ResponseWriter, r *http.Request, id string) (*models.Excuse, bool) {
+	excuse, err := h.storage.GetExcuse(r.Context(), id)
+	if err != nil {
+		storageErrorResponse(w, err, "Failed to get excuse")
+		return nil, false
+	}
+	if excuse.Status != models.StatusApproved {
+		utils.ErrorResponse(w, http.StatusNotFound, "Excuse not found")
+		return nil, false
+	}
+	return excuse, true
+}
+
+// ReplaceExcuse (PUT) заменяет все редактируемые поля; отсутствующие поля
+// получают значения по умолчанию, как при создании.
+func (h *ExcuseHandler) ReplaceExcuse(w http.ResponseWriter, r *http.Request) {
+	var req models.ExcuseRequest
+	if err := utils.JSONDecode(r.Body, &req); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	h.update(w, r, req)
+}
+
+// PatchExcuse (PATCH) меняет только переданные поля. Результат проходит
+// ту же проверку, что и при создании.
+func (h *ExcuseHandler) PatchExcuse(w http.ResponseWriter, r *http.Request) {
+	var patch models.ExcusePatch
+	if err := utils.JSONDecode(r.Body, &patch); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	current, err := h.storage.GetExcuse(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		storageErrorResponse(w, err, "Failed to get excuse")
+		return
+	}
+
+	req := models.ExcuseRequest{
+		Text:     current.Text,
+		Category: current.Category,
+		Language: current.Language,
+		Severity: current.Severity,
+		Tags:     current.Tags,
+	}
+	if patch.Text != nil {
+		req.Text = *patch.Text
+	}
+	if patch.Category != nil {
+		req.Category = *patch.Category
+	}
+	if patch.Language != nil {
+		req.Language = *patch.Language
+	}
+	if patch.Severity != nil {
+		req.Severity = *patch.Severity
+	}
+	if patch.Tags != nil {
+		req.Tags = *patch.Tags
+	}
+
+	h.update(w, r, req)
+}
+
+func (h *ExcuseHandler) update(w http.ResponseWriter, r *http.Request, req models.ExcuseRequest) {
+	if err := prepareExcuseRequest(&req, h.catalog); err != nil {
+		utils.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !h.checkContent(w, &req) {
+		return
+	}
+	id := mux.Vars(r)["id"]
+	if !h.checkDuplicate(w, r, req.Text, id) {
+		return
+	}
+
+	excuse, err := h.storage.UpdateExcuse(r.Context(), models.Excuse{
+		ID:       id,
+		Text:     req.Text,
+		Category: req.Category,
+		Language: req.Language,
+		Severity: req.Severity,
+		Tags:     req.Tags,
+	})
+	if err != nil {
+		storageErrorResponse(w, err, "Failed to update excuse")
+		return
+	}
+
+	logger.LogExcuseRequest(excuse, "UPDATE")
+	utils.JSONResponse(w, http.StatusOK, excuse)
+}
+
+// DeleteExcuse удаляет оправдание.
+func (h *ExcuseHandler) DeleteExcuse(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if err := h.storage.DeleteExcuse(r.Context(), id); err != nil {
+		storageErrorResponse(w, err, "Failed to delete excuse")
+		return
+	}
+
+	logger.Info.Printf("Excuse DELETE: %s", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// prepareExcuseRequest подставляет значения по умолчанию из справочников
+// и проверяет запрос на создание или изменение оправдания.
+func prepareExcuseRequest(req *models.ExcuseRequest, cat *catalog.Catalog) error {
+	if req.Text == "" {
+		return errors.New("Text is required")
+	}
+
+	req.Category = strings.ToLower(req.Category)
+	req.Language = strings.ToLower(req.Language)
+	req.Severity = strings.ToLower(req.Severity)
+
+	if req.Category == "" {
+		req.Category = cat.Defaults.Category
+	}
+	if req.Language == "" {
+		req.Language = cat.Defaults.Language
+	}
+	if req.Severity == "" {
+		req.Severity = cat.Defaults.Severity
+	}
+
+	if !cat.ValidCategory(req.Category) {
+		return errors.New("Invalid category")
+	}
+	if !cat.ValidLanguage(req.Language) {
+		return errors.New("Invalid language")
+	}
+	if !cat.ValidSeverity(req.Severity) {
+		return errors.New("Invalid severity")
+	}
+
+	tags, err := storage.NormalizeTags(req.Tags)
+	if err != nil {
+		return fmt.Errorf("Invalid tags: %v", err)
+	}
+	req.Tags = tags
+	return nil
 }
 
 // RateExcuse обрабатывает оценку (лайк/дизлайк) оправдания.
+// У каждого голосующего (см. voters) один голос, который можно изменить
+// повторным запросом или отозвать голосом 0.
 func (h *ExcuseHandler) RateExcuse(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-
 	var req models.RatingRequest
 	if err := utils.JSONDecode(r.Body, &req); err != nil {
 		utils.ErrorResponse(w, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
 
-	change := 1
+	vote := 1
 	if !req.Upvote {
-		change = -1
+		vote = -1
+	}
+	if req.Vote != nil {
+		vote = *req.Vote
+	}
+	if vote < -1 || vote > 1 {
+		utils.ErrorResponse(w, http.StatusBadRequest, "Vote must be 1, -1 or 0")
+		return
 	}
 
-	if err := h.storage.RateExcuse(id, change); err != nil {
-		if err == sql.ErrNoRows {
-			utils.ErrorResponse(w, http.StatusNotFound, "Excuse not found")
-			return
-		}
-		logger.Error.Printf("Failed to rate excuse %s: %v", id, err)
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Failed to rate excuse")
+	h.vote(w, r, vote)
+}
+
+// RetractRating отзывает голос клиента за оправдание.
+func (h *ExcuseHandler) RetractRating(w http.ResponseWriter, r *http.Request) {
+	h.vote(w, r, 0)
+}
+
+func (h *ExcuseHandler) vote(w http.ResponseWriter, r *http.Request, vote int) {
+	id := mux.Vars(r)["id"]
+	if _, ok := h.published(w, r, id); !ok {
+		return
+	}
+
+	rating, err := h.storage.RateExcuse(r.Context(), id, h.voters.id(w, r), vote)
+	if err != nil {
+		storageErrorResponse(w, err, "Failed to rate excuse")
 		return
 	}
 
-	utils.JSONResponse(w, http.StatusOK, map[string]string{"message": "Rating updated"})
+	utils.JSONResponse(w, http.StatusOK, models.RatingResult{ID: id, Rating: rating, Vote: vote})
 }
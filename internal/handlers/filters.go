@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"procrastigo/internal/catalog"
+	"procrastigo/internal/models"
+	"procrastigo/internal/storage"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseExcuseFilter читает и проверяет параметры фильтрации из запроса:
+// category, lang, severity (через запятую или повтором параметра),
+// created_after и created_before (RFC3339 или YYYY-MM-DD), min_rating,
+// tags (через запятую) и tags_mode (any или all).
+// Допустимые значения берутся из справочников cat. Фильтр всегда
+// ограничен одобренными оправданиями: остальные видны только в админ API.
+func parseExcuseFilter(r *http.Request, cat *catalog.Catalog) (storage.ExcuseFilter, error) {
+	query := r.URL.Query()
+	filter := storage.ExcuseFilter{
+		Category: strings.ToLower(query.Get("category")),
+		Language: strings.ToLower(query.Get("lang")),
+		Status:   models.StatusApproved,
+	}
+
+	if filter.Category != "" && !cat.ValidCategory(filter.Category) {
+		return filter, errors.New("Invalid category")
+	}
+	if filter.Language != "" && !cat.ValidLanguage(filter.Language) {
+		return filter, errors.New("Invalid language")
+	}
+
+	for _, severity := range splitList(query["severity"]) {
+		if !cat.ValidSeverity(severity) {
+			return filter, fmt.Errorf("Invalid severity: %s", severity)
+		}
+		filter.Severities = append(filter.Severities, severity)
+	}
+
+	var err error
+	if filter.CreatedAfter, err = parseTimeParam(query.Get("created_after")); err != nil {
+		return filter, fmt.Errorf("Invalid created_after: %v", err)
+	}
+	if filter.CreatedBefore, err = parseTimeParam(query.Get("created_before")); err != nil {
+		return filter, fmt.Errorf("Invalid created_before: %v", err)
+	}
+
+	if value := query.Get("min_rating"); value != "" {
+		minRating, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, errors.New("Invalid min_rating")
+		}
+		filter.MinRating = &minRating
+	}
+
+	tags, err := storage.NormalizeTags(splitList(query["tags"]))
+	if err != nil {
+		return filter, fmt.Errorf("Invalid tags: %v", err)
+	}
+	filter.Tags = tags
+	filter.TagMode = strings.ToLower(query.Get("tags_mode"))
+	if filter.TagMode == "" {
+		filter.TagMode = storage.TagModeAny
+	}
+	if !storage.ValidTagMode(filter.TagMode) {
+		return filter, errors.New("Invalid tags_mode")
+	}
+
+	return filter, nil
+}
+
+// splitList разбивает значения вида "a,b" из всех повторов параметра
+func splitList(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			item = strings.ToLower(strings.TrimSpace(item))
+			if item != "" {
+				result = append(result, item)
+			}
+		}
+	}
+	return result
+}
+
+// parseTimeParam принимает RFC3339 или дату YYYY-MM-DD (полночь UTC)
+func parseTimeParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, errors.New("expected RFC3339 timestamp or YYYY-MM-DD date")
+	}
+	return t, nil
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"procrastigo/internal/catalog"
+	"procrastigo/internal/config"
+	"procrastigo/internal/models"
+	"procrastigo/internal/storage"
+	"procrastigo/pkg/logger"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testToken = "secret"
+
+func TestMain(m *testing.M) {
+	logger.Init("error")
+	os.Exit(m.Run())
+}
+
+// newTestAPI собирает роутер поверх memory хранилища с excuses.
+// configure меняет конфигурацию по умолчанию (admin.token уже задан).
+func newTestAPI(t *testing.T, excuses []models.Excuse, configure func(cfg *config.Config)) (http.Handler, storage.Storage) {
+	t.Helper()
+	cfg, err := config.Load(config.DefaultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Admin.Token = testToken
+	if configure != nil {
+		configure(cfg)
+	}
+
+	cat, err := catalog.New(
+		catalog.Defaults{Category: "work", Language: "en", Severity: "low"},
+		[]catalog.Entry{{Code: "work"}}, []catalog.Entry{{Code: "en"}}, []catalog.Entry{{Code: "low"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := storage.NewMemoryStorage()
+	for _, excuse := range excuses {
+		if err := store.CreateExcuse(context.Background(), excuse); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewRouter(store, cat, cfg), store
+}
+
+// testExcuse - опубликованное оправдание с заданным текстом
+func testExcuse(id, text string) models.Excuse {
+	return models.Excuse{ID: id, Text: text, Category: "work", Language: "en", Severity: "low",
+		CreatedAt: time.Now().UTC(), Status: models.StatusApproved}
+}
+
+// send выполняет запрос; пустой token - без заголовка Authorization
+func send(api http.Handler, method, path, body, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestEditsRequireAdminToken(t *testing.T) {
+	api, store := newTestAPI(t, []models.Excuse{testExcuse("e1", "My cat sat on the keyboard")}, nil)
+	patch := `{"text": "My dog ate the network cable"}`
+
+	for _, tc := range []struct{ method, body string }{
+		{"PATCH", patch},
+		{"PUT", patch},
+		{"DELETE", ""},
+	} {
+		if rec := send(api, tc.method, "/api/v1/excuses/e1", tc.body, ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("anonymous %s: got %d, want 401", tc.method, rec.Code)
+		}
+		if rec := send(api, tc.method, "/api/v1/excuses/e1", tc.body, "wrong"); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s with wrong token: got %d, want 401", tc.method, rec.Code)
+		}
+	}
+	if got, err := store.GetExcuse(context.Background(), "e1"); err != nil || got.Text != "My cat sat on the keyboard" {
+		t.Fatalf("excuse changed by anonymous requests: %+v, %v", got, err)
+	}
+
+	if rec := send(api, "PATCH", "/api/v1/excuses/e1", patch, testToken); rec.Code != http.StatusOK {
+		t.Errorf("PATCH with token: got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := send(api, "DELETE", "/api/v1/excuses/e1", "", testToken); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE with token: got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestEditsOpenWithoutAdminToken(t *testing.T) {
+	api, _ := newTestAPI(t, []models.Excuse{testExcuse("e1", "My cat sat on the keyboard")}, func(cfg *config.Config) {
+		cfg.Admin.Token = ""
+	})
+
+	if rec := send(api, "PATCH", "/api/v1/excuses/e1", `{"text": "My dog ate the network cable"}`, ""); rec.Code != http.StatusOK {
+		t.Errorf("PATCH without admin.token: got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := send(api, "DELETE", "/api/v1/excuses/e1", "", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE without admin.token: got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := send(api, "GET", "/api/v1/admin/moderation", "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("admin API without admin.token: got %d, want 404", rec.Code)
+	}
+}
+
+func TestUpdateRejectsNearDuplicate(t *testing.T) {
+	api, _ := newTestAPI(t, []models.Excuse{
+		testExcuse("e1", "My cat sat on the keyboard"),
+		testExcuse("e2", "The printer ran out of toner again"),
+	}, nil)
+
+	rec := send(api, "PATCH", "/api/v1/excuses/e2", `{"text": "My cat sat on the keyboard!"}`, testToken)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), `"id":"e1"`) {
+		t.Errorf("PATCH into a copy of e1: got %d: %s", rec.Code, rec.Body)
+	}
+
+	// похожесть на собственный старый текст не мешает исправить опечатку
+	rec = send(api, "PATCH", "/api/v1/excuses/e1", `{"text": "My cat sat on the keyboard."}`, testToken)
+	if rec.Code != http.StatusOK {
+		t.Errorf("typo fix: got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestModerationHidesUnpublished(t *testing.T) {
+	pending := testExcuse("p1", "The build server is on fire again")
+	pending.Status = models.StatusPending
+	api, store := newTestAPI(t, []models.Excuse{testExcuse("a1", "My cat sat on the keyboard"), pending}, func(cfg *config.Config) {
+		cfg.Moderation.Enabled = true
+	})
+
+	// публичные методы не видят неодобренное оправдание
+	for _, tc := range []struct{ method, path, body string }{
+		{"GET", "/api/v1/excuses/p1", ""},
+		{"POST", "/api/v1/excuses/p1/rate", `{"vote": 1}`},
+		{"DELETE", "/api/v1/excuses/p1/rate", ""},
+	} {
+		if rec := send(api, tc.method, tc.path, tc.body, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("%s %s: got %d, want 404: %s", tc.method, tc.path, rec.Code, rec.Body)
+		}
+	}
+
+	// анонимная правка не обходит модерацию
+	for _, id := range []string{"a1", "p1"} {
+		rec := send(api, "PATCH", "/api/v1/excuses/"+id, `{"text": "Buy cheap watches today"}`, "")
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("anonymous PATCH %s: got %d, want 401", id, rec.Code)
+		}
+	}
+	rec := send(api, "GET", "/api/v1/excuses/a1", "", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "My cat sat on the keyboard") {
+		t.Errorf("approved excuse after anonymous PATCH: %d %s", rec.Code, rec.Body)
+	}
+
+	rec = send(api, "GET", "/api/v1/stats", "", "")
+	if !strings.Contains(rec.Body.String(), `"total_excuses":1`) {
+		t.Errorf("stats must count approved excuses only: %s", rec.Body)
+	}
+
+	// новое оправдание ждет модератора
+	rec = send(api, "POST", "/api/v1/excuses", `{"text": "The VPN ate my homework"}`, "")
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("create with moderation: got %d: %s", rec.Code, rec.Body)
+	}
+	page, err := store.GetExcuses(context.Background(), storage.ExcuseFilter{Status: models.StatusPending}, storage.PageRequest{})
+	if err != nil || len(page.Excuses) != 2 {
+		t.Fatalf("pending queue: %v, %v", page, err)
+	}
+
+	if rec := send(api, "POST", "/api/v1/admin/moderation/p1/approve", "", testToken); rec.Code != http.StatusOK {
+		t.Fatalf("approve: got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := send(api, "GET", "/api/v1/excuses/p1", "", ""); rec.Code != http.StatusOK {
+		t.Errorf("approved excuse: got %d", rec.Code)
+	}
+	if rec := send(api, "POST", "/api/v1/excuses/p1/rate", `{"vote": 1}`, ""); rec.Code != http.StatusOK {
+		t.Errorf("vote for approved excuse: got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestVotersIgnoreUnknownKeysAndForgedCookies(t *testing.T) {
+	api, store := newTestAPI(t, []models.Excuse{testExcuse("e1", "My cat sat on the keyboard")}, func(cfg *config.Config) {
+		cfg.Voting.APIKeys = []string{"known-key"}
+	})
+	rate := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/excuses/e1/rate", strings.NewReader(`{"vote": 1}`))
+		req.RemoteAddr = "192.0.2.1:1234"
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// первый голос выдает подписанную cookie
+	first := rate("", "")
+	cookies := first.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "procrastigo_voter" {
+		t.Fatalf("first vote: want voter cookie, got %v", cookies)
+	}
+	// новые ключи и поддельные cookie с того же адреса - тот же голосующий
+	rate("X-API-Key", "random-1")
+	rate("X-API-Key", "random-2")
+	rate("Cookie", "procrastigo_voter=ip:forged.deadbeef")
+	if got, _ := store.GetExcuse(context.Background(), "e1"); got.Rating != 1 {
+		t.Fatalf("unknown keys and forged cookies added votes: rating %d", got.Rating)
+	}
+
+	// cookie остается действительной и с другого адреса
+	req := httptest.NewRequest("POST", "/api/v1/excuses/e1/rate", strings.NewReader(`{"vote": -1}`))
+	req.RemoteAddr = "198.51.100.7:1234"
+	req.AddCookie(cookies[0])
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"rating":-1`) {
+		t.Fatalf("vote with cookie from another address: %d %s", rec.Code, rec.Body)
+	}
+
+	// настроенный ключ голосует от своего имени
+	if rec := rate("X-API-Key", "known-key"); !strings.Contains(rec.Body.String(), `"rating":0`) {
+		t.Fatalf("vote with configured key: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestInvalidErrorsDoNotLeakDriverText(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want string
+	}{
+		{storage.Invalidf("unknown sort %q", "x"), `Invalid argument: unknown sort \"x\"`},
+		{fmt.Errorf("failed to list: %w", storage.ErrInvalidCursor), "Invalid argument: invalid cursor"},
+		// так dbErr оборачивает нарушение ограничения базы
+		{fmt.Errorf("failed to create: %w", fmt.Errorf("%w: %v", storage.ErrInvalid, errors.New(`pq: value too long for type character varying(32)`))), "Invalid excuse data"},
+	} {
+		rec := httptest.NewRecorder()
+		storageErrorResponse(rec, tt.err, "Failed")
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tt.want) || strings.Contains(rec.Body.String(), "pq:") {
+			t.Errorf("%v: got %d %s, want %q", tt.err, rec.Code, rec.Body, tt.want)
+		}
+	}
+}
@@ -1,8 +1,12 @@
 package handlers
 
 import (
+	"context"
+	"crypto/subtle"
 	"log"
 	"net/http"
+	"procrastigo/pkg/utils"
+	"strings"
 	"time"
 )
 
@@ -18,10 +22,10 @@ func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Разрешаем все источники
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		// Разрешаем методы GET, POST и OPTIONS
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		// Разрешаем заголовки Content-Type
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		// Разрешаем методы чтения и изменения оправданий
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		// Разрешаем заголовки Content-Type, Authorization, X-API-Key и X-Client-ID
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, X-Client-ID")
 
 		// ОБРАБОТКА OPTIONS (Preflight Request)
 		if r.Method == "OPTIONS" {
@@ -33,3 +37,45 @@ func CORSMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// AdminMiddleware пускает только запросы с заголовком
+// Authorization: Bearer <token>. Пустой token выключает админ API.
+func AdminMiddleware(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				utils.ErrorResponse(w, http.StatusNotFound, "Admin API is disabled")
+				return
+			}
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				utils.ErrorResponse(w, http.StatusUnauthorized, "Invalid admin token")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// EditorMiddleware защищает изменение и удаление оправданий. С token это
+// AdminMiddleware; без него (админ API и модерация выключены) изменения
+// открыты всем, как до появления админ API.
+func EditorMiddleware(token string) func(http.Handler) http.Handler {
+	if token == "" {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return AdminMiddleware(token)
+}
+
+// TimeoutMiddleware ограничивает время обработки запроса: контекст запроса
+// получает дедлайн, и обращения к хранилищу прерываются по его истечении.
+func TimeoutMiddleware(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
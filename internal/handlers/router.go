@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"procrastigo/internal/catalog"
+	"procrastigo/internal/config"
+	"procrastigo/internal/storage"
+	"procrastigo/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// NewRouter собирает все маршруты /api/v1 с middleware.
+func NewRouter(store storage.Storage, cat *catalog.Catalog, cfg *config.Config) *mux.Router {
+	excuseHandler := NewExcuseHandler(store, cat, cfg)
+	statsHandler := NewStatsHandler(store)
+	catalogHandler := NewCatalogHandler(cat)
+	tagHandler := NewTagHandler(store)
+	adminHandler := NewAdminHandler(store, cfg)
+	requireAdmin := AdminMiddleware(cfg.Admin.Token)
+	requireEditor := EditorMiddleware(cfg.Admin.Token)
+	if cfg.Admin.Token == "" {
+		logger.Warn.Printf("admin.token is not set: anyone can edit and delete excuses")
+	}
+
+	router := mux.NewRouter()
+
+	v1 := router.PathPrefix("/api/v1").Subrouter()
+
+	v1.HandleFunc("/excuses/random", excuseHandler.GetRandomExcuse).Methods("GET")
+	v1.HandleFunc("/excuses/daily", excuseHandler.GetDailyExcuse).Methods("GET")
+	v1.HandleFunc("/excuses/search", excuseHandler.SearchExcuses).Methods("GET")
+	v1.HandleFunc("/excuses", excuseHandler.GetExcuses).Methods("GET")
+	v1.HandleFunc("/excuses", excuseHandler.CreateExcuse).Methods("POST")
+	v1.HandleFunc("/excuses/{id}", excuseHandler.GetExcuse).Methods("GET")
+	// с admin.token изменять и удалять оправдания могут только модераторы
+	v1.Handle("/excuses/{id}", requireEditor(http.HandlerFunc(excuseHandler.ReplaceExcuse))).Methods("PUT")
+	v1.Handle("/excuses/{id}", requireEditor(http.HandlerFunc(excuseHandler.PatchExcuse))).Methods("PATCH")
+	v1.Handle("/excuses/{id}", requireEditor(http.HandlerFunc(excuseHandler.DeleteExcuse))).Methods("DELETE")
+	v1.HandleFunc("/excuses/{id}/rate", excuseHandler.RateExcuse).Methods("POST")
+	v1.HandleFunc("/excuses/{id}/rate", excuseHandler.RetractRating).Methods("DELETE")
+
+	v1.HandleFunc("/stats", statsHandler.GetStats).Methods("GET")
+
+	v1.HandleFunc("/categories", catalogHandler.GetCategories).Methods("GET")
+	v1.HandleFunc("/languages", catalogHandler.GetLanguages).Methods("GET")
+	v1.HandleFunc("/severities", catalogHandler.GetSeverities).Methods("GET")
+	v1.HandleFunc("/tags", tagHandler.GetTags).Methods("GET")
+
+	admin := v1.PathPrefix("/admin").Subrouter()
+	admin.Use(requireAdmin)
+	admin.HandleFunc("/duplicates", adminHandler.GetDuplicates).Methods("GET")
+	admin.HandleFunc("/moderation", adminHandler.GetModerationQueue).Methods("GET")
+	admin.HandleFunc("/moderation/{id}/approve", adminHandler.ApproveExcuse).Methods("POST")
+	admin.HandleFunc("/moderation/{id}/reject", adminHandler.RejectExcuse).Methods("POST")
+
+	router.Use(LoggingMiddleware)
+	router.Use(CORSMiddleware)
+	router.Use(TimeoutMiddleware(cfg.StorageTimeout()))
+
+	return router
+}
@@ -15,9 +15,9 @@ func NewStatsHandler(storage storage.Storage) *StatsHandler {
 }
 
 func (h *StatsHandler) GetStats(w http.ResponseWriter, r *http.Request) {
-	stats, err := h.storage.GetStats()
+	stats, err := h.storage.GetStats(r.Context())
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		storageErrorResponse(w, err, "Failed to get stats")
 		return
 	}
 
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+	"procrastigo/internal/storage"
+	"procrastigo/pkg/utils"
+)
+
+// TagHandler отдает используемые теги с числом оправданий.
+type TagHandler struct {
+	storage storage.Storage
+}
+
+func NewTagHandler(storage storage.Storage) *TagHandler {
+	return &TagHandler{storage: storage}
+}
+
+func (h *TagHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.storage.GetTags(r.Context())
+	if err != nil {
+		storageErrorResponse(w, err, "Failed to get tags")
+		return
+	}
+
+	utils.JSONResponse(w, http.StatusOK, tags)
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"procrastigo/internal/config"
+	"strings"
+)
+
+const (
+	apiKeyHeader    = "X-API-Key"
+	voterCookieName = "procrastigo_voter"
+	voterCookieAge  = 365 * 24 * 60 * 60
+)
+
+// voters определяет, от чьего имени голосует запрос. По убыванию
+// надежности: настроенный API ключ из заголовка X-API-Key, подписанная
+// cookie procrastigo_voter, IP адрес клиента. Незнакомые ключи и cookie с
+// неверной подписью игнорируются, иначе каждый новый ключ давал бы новый
+// голос. Ключи и адреса хранятся только в виде хешей.
+type voters struct {
+	keys   map[string]bool // хеши voting.api_keys
+	secret []byte          // ключ подписи cookie
+}
+
+func newVoters(cfg *config.Config) *voters {
+	v := &voters{keys: make(map[string]bool), secret: []byte(cfg.Voting.CookieSecret)}
+	for _, key := range cfg.Voting.APIKeys {
+		v.keys[shortHash(key)] = true
+	}
+	if len(v.secret) == 0 {
+		// cookie перестанут приниматься после перезапуска, и голосующие
+		// снова будут различаться по IP, пока не получат новую
+		v.secret = make([]byte, 32)
+		if _, err := rand.Read(v.secret); err != nil {
+			panic(err)
+		}
+	}
+	return v
+}
+
+// id возвращает голосующего. Голосующему без подписанной cookie она
+// выдается: в ней записан тот же идентификатор по IP, поэтому очистка
+// cookie не дает лишнего голоса, а смена сети не отнимает уже отданный.
+func (v *voters) id(w http.ResponseWriter, r *http.Request) string {
+	if key := r.Header.Get(apiKeyHeader); key != "" && v.keys[shortHash(key)] {
+		return "key:" + shortHash(key)
+	}
+
+	if cookie, err := r.Cookie(voterCookieName); err == nil {
+		if id, ok := v.verify(cookie.Value); ok {
+			return id
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	id := "ip:" + shortHash(host)
+	http.SetCookie(w, &http.Cookie{
+		Name:     voterCookieName,
+		Value:    id + "." + v.sign(id),
+		Path:     "/",
+		MaxAge:   voterCookieAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return id
+}
+
+// verify проверяет cookie вида <id>.<подпись> и возвращает id
+func (v *voters) verify(value string) (string, bool) {
+	i := strings.LastIndexByte(value, '.')
+	if i < 0 {
+		return "", false
+	}
+	id, sig := value[:i], value[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(v.sign(id))) {
+		return "", false
+	}
+	return id, true
+}
+
+func (v *voters) sign(id string) string {
+	mac := hmac.New(sha256.New, v.secret)
+	mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// shortHash возвращает первые 16 байт SHA-256 в hex
+func shortHash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:16])
+}
@@ -3,24 +3,63 @@ package models
 import "time"
 
 type Excuse struct {
-	ID        string    `json:"id"`
-	Text      string    `json:"text"`
-	Category  string    `json:"category"`
-	Language  string    `json:"language"`
-	Severity  string    `json:"severity"`
-	CreatedAt time.Time `json:"created_at"`
-	Rating    int       `json:"rating"`
+	ID              string    `json:"id"`
+	Text            string    `json:"text"`
+	Category        string    `json:"category"`
+	Language        string    `json:"language"`
+	Severity        string    `json:"severity"`
+	CreatedAt       time.Time `json:"created_at"`
+	Rating          int       `json:"rating"`
+	Tags            []string  `json:"tags,omitempty"`             // в нижнем регистре, по алфавиту
+	Status          string    `json:"status"`                     // статус модерации; пустой считается StatusApproved
+	RejectionReason string    `json:"rejection_reason,omitempty"` // причина отказа для StatusRejected
 }
 
+// Статусы модерации. Публичные методы API показывают только одобренные.
+const (
+	StatusApproved = "approved"
+	StatusPending  = "pending"
+	StatusRejected = "rejected"
+)
+
 type ExcuseRequest struct {
-	Text     string `json:"text"`
-	Category string `json:"category"`
-	Language string `json:"language"`
-	Severity string `json:"severity"`
+	Text     string   `json:"text"`
+	Category string   `json:"category"`
+	Language string   `json:"language"`
+	Severity string   `json:"severity"`
+	Tags     []string `json:"tags"`
+}
+
+// DailyExcuse - оправдание дня для даты Date (YYYY-MM-DD) в поясе Timezone
+type DailyExcuse struct {
+	Date     string `json:"date"`
+	Timezone string `json:"timezone"`
+	Excuse   Excuse `json:"excuse"`
+}
+
+// ExcusePatch - частичное изменение оправдания; nil поля не меняются
+type ExcusePatch struct {
+	Text     *string   `json:"text"`
+	Category *string   `json:"category"`
+	Language *string   `json:"language"`
+	Severity *string   `json:"severity"`
+	Tags     *[]string `json:"tags"`
+}
+
+// RejectRequest - тело запроса на отклонение оправдания модератором
+type RejectRequest struct {
+	Reason string `json:"reason"`
 }
 
 type RatingRequest struct {
 	Upvote bool `json:"upvote"`
+	Vote   *int `json:"vote,omitempty"` // 1, -1 или 0 (отозвать голос); важнее Upvote
+}
+
+type RatingResult struct {
+	ID     string `json:"id"`
+	Rating int    `json:"rating"`
+	Vote   int    `json:"vote"`
 }
 
 type Stats struct {
@@ -29,3 +68,44 @@ type Stats struct {
 	ExcusesToday               int    `json:"excuses_today"`
 	GlobalProcrastinationLevel string `json:"global_procrastination_level"`
 }
+
+// SearchHit - найденное оправдание. Highlight - текст, в котором найденные
+// слова обернуты в <mark></mark>.
+type SearchHit struct {
+	Excuse    Excuse  `json:"excuse"`
+	Score     float64 `json:"score"`
+	Highlight string  `json:"highlight"`
+}
+
+// SearchResult - ответ полнотекстового поиска
+type SearchResult struct {
+	Query   string      `json:"query"`
+	Results []SearchHit `json:"results"`
+}
+
+// SimilarExcuse - существующее оправдание, похожее на проверяемый текст;
+// Similarity от 0 до 1
+type SimilarExcuse struct {
+	Excuse     Excuse  `json:"excuse"`
+	Similarity float64 `json:"similarity"`
+}
+
+// DuplicateCluster - группа оправданий, связанных цепочкой похожих пар.
+// Excuses упорядочены по created_at: первое - самое раннее. Similarity -
+// наибольшее сходство пары внутри группы.
+type DuplicateCluster struct {
+	Excuses    []Excuse `json:"excuses"`
+	Similarity float64  `json:"similarity"`
+}
+
+// TagCount - тег и число оправданий с ним
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// ExcusePage - страница списка оправданий. NextCursor пуст на последней странице.
+type ExcusePage struct {
+	Excuses    []Excuse `json:"excuses"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
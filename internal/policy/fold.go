@@ -0,0 +1,27 @@
+package policy
+
+import (
+	"strings"
+	"unicode"
+)
+
+// homoglyphs сводит строчные кириллические буквы к похожим латинским.
+// Результат Fold не предназначен для показа: важно лишь, что "спам" и
+// "cпaм" (латинские c и a) дают одну и ту же строку.
+var homoglyphs = map[rune]rune{
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h',
+	'о': 'o', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x',
+	'і': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w', 'ӏ': 'l',
+}
+
+// Fold приводит слово к виду для сравнения: нижний регистр, ё как е
+// и латинские буквы вместо кириллических двойников.
+func Fold(word string) string {
+	return strings.Map(func(r rune) rune {
+		r = unicode.ToLower(r)
+		if latin, ok := homoglyphs[r]; ok {
+			return latin
+		}
+		return r
+	}, word)
+}
@@ -0,0 +1,62 @@
+// Package policy проверяет текст оправдания перед сохранением. Политика -
+// цепочка правил (Rule): каждое может поправить текст (например, вырезать
+// ссылки) и вернуть нарушения с кодами, по которым клиент объясняет отказ.
+package policy
+
+// Коды нарушений
+const (
+	CodeTooShort        = "text_too_short"
+	CodeTooLong         = "text_too_long"
+	CodeBannedWord      = "banned_word"
+	CodeURLNotAllowed   = "url_not_allowed"
+	CodeEmailNotAllowed = "email_not_allowed"
+)
+
+// Violation - одно нарушение политики
+type Violation struct {
+	Rule    string `json:"rule"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Content - проверяемое оправдание. Правила могут менять Text.
+type Content struct {
+	Text     string
+	Language string
+}
+
+// Rule - одно правило политики. Rule в нарушениях заполняет Pipeline.
+type Rule interface {
+	Name() string
+	Check(c *Content) []Violation
+}
+
+// Pipeline прогоняет текст через правила в заданном порядке.
+type Pipeline struct {
+	rules []Rule
+}
+
+// New собирает политику из правил; nil правила пропускаются.
+func New(rules ...Rule) *Pipeline {
+	p := &Pipeline{}
+	for _, rule := range rules {
+		if rule != nil {
+			p.rules = append(p.rules, rule)
+		}
+	}
+	return p
+}
+
+// Check применяет все правила и возвращает все нарушения сразу, чтобы
+// клиенту не приходилось исправлять текст по одной ошибке. Пустой результат -
+// текст (возможно, исправленный в c.Text) можно сохранять.
+func (p *Pipeline) Check(c *Content) []Violation {
+	var violations []Violation
+	for _, rule := range p.rules {
+		for _, v := range rule.Check(c) {
+			v.Rule = rule.Name()
+			violations = append(violations, v)
+		}
+	}
+	return violations
+}
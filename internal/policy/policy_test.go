@@ -0,0 +1,111 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func codes(violations []Violation) []string {
+	var result []string
+	for _, v := range violations {
+		result = append(result, v.Rule+"/"+v.Code)
+	}
+	return result
+}
+
+func TestFoldHomoglyphs(t *testing.T) {
+	cases := []struct {
+		a, b string
+	}{
+		{"спам", "cпaм"}, // латинские c и a
+		{"СПАМ", "спам"},
+		{"ёжик", "ежик"},
+		{"Spam", "spam"},
+	}
+	for _, tc := range cases {
+		if Fold(tc.a) != Fold(tc.b) {
+			t.Errorf("Fold(%q) = %q, Fold(%q) = %q; want equal", tc.a, Fold(tc.a), tc.b, Fold(tc.b))
+		}
+	}
+}
+
+func TestBannedWords(t *testing.T) {
+	rule := NewBannedWords(map[string][]string{
+		"ru": {"спам", "реклам*"},
+		"en": {"spam"},
+	})
+	cases := []struct {
+		text, language string
+		want           int
+	}{
+		{"Опоздал из-за спама", "ru", 0}, // только слово целиком
+		{"Опоздал из-за СПАМ", "ru", 1},
+		{"Опоздал из-за cпaм", "ru", 1},       // латинские c и a
+		{"Опоздал из-за сп\u200bам", "ru", 1}, // zero width space внутри слова
+		{"Смотрел рекламу, рекламу и рекламщиков", "ru", 2},
+		{"Too much SPAM today", "en", 1},
+		// язык задает клиент, поэтому проверяются все списки
+		{"Опоздал из-за спам", "en", 1},
+		{"Too much spam today", "xx", 1},
+		{"Too much spam and реклама", "", 2},
+	}
+	for _, tc := range cases {
+		got := rule.Check(&Content{Text: tc.text, Language: tc.language})
+		if len(got) != tc.want {
+			t.Errorf("%q (%s): got %v, want %d violations", tc.text, tc.language, got, tc.want)
+		}
+	}
+}
+
+func TestLinks(t *testing.T) {
+	text := "Читал https://example.com/a?b=1 и писал на boss@example.com, потом www.test.org и news.ru"
+
+	c := &Content{Text: text}
+	got := Links{Mode: LinksReject}.Check(c)
+	if want := []string{"/" + CodeURLNotAllowed, "/" + CodeEmailNotAllowed}; !reflect.DeepEqual(codes(got), want) {
+		t.Errorf("reject: got %v, want %v", codes(got), want)
+	}
+	if c.Text != text {
+		t.Errorf("reject must not change text: %q", c.Text)
+	}
+
+	c = &Content{Text: text}
+	if got := (Links{Mode: LinksStrip}).Check(c); len(got) != 0 {
+		t.Errorf("strip: unexpected violations %v", got)
+	}
+	if want := "Читал и писал на , потом и"; c.Text != want {
+		t.Errorf("strip: got %q, want %q", c.Text, want)
+	}
+
+	for _, clean := range []string{"Не успел, т.е. совсем", "Версия 1.2.3 сломалась", "Node.js упал"} {
+		if got := (Links{Mode: LinksReject}).Check(&Content{Text: clean}); len(got) != 0 {
+			t.Errorf("%q: false positive %v", clean, got)
+		}
+	}
+}
+
+func TestPipelineCollectsAllViolations(t *testing.T) {
+	p := New(
+		Links{Mode: LinksStrip},
+		NewBannedWords(map[string][]string{"en": {"spam"}}),
+		Length{Min: 10, Max: 100},
+	)
+
+	// после вырезания ссылки текст становится слишком коротким
+	c := &Content{Text: "spam http://spam.example.com", Language: "en"}
+	got := codes(p.Check(c))
+	want := []string{"banned_words/" + CodeBannedWord, "length/" + CodeTooShort}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// без нижней границы пустой текст все равно не проходит
+	if got := codes(New(Links{Mode: LinksStrip}, Length{}).Check(&Content{Text: "www.example.com"})); !reflect.DeepEqual(got, []string{"length/" + CodeTooShort}) {
+		t.Errorf("only a link: got %v", got)
+	}
+
+	c = &Content{Text: "My cat sat on the keyboard", Language: "en"}
+	if got := p.Check(c); len(got) != 0 {
+		t.Errorf("clean text: %v", got)
+	}
+}
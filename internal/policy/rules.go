@@ -0,0 +1,160 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Length ограничивает длину текста в символах без пробелов по краям.
+// 0 - без ограничения, но пустой текст (например, из одних вырезанных
+// ссылок) не проходит никогда.
+type Length struct {
+	Min, Max int
+}
+
+func (Length) Name() string { return "length" }
+
+func (l Length) Check(c *Content) []Violation {
+	n := utf8.RuneCountInString(strings.TrimSpace(c.Text))
+	min := l.Min
+	if min < 1 {
+		min = 1
+	}
+	switch {
+	case n < min:
+		return []Violation{{Code: CodeTooShort, Message: fmt.Sprintf("Text must be at least %d characters", min)}}
+	case l.Max > 0 && n > l.Max:
+		return []Violation{{Code: CodeTooLong, Message: fmt.Sprintf("Text must be at most %d characters", l.Max)}}
+	}
+	return nil
+}
+
+// Что делать со ссылками и адресами почты в тексте
+const (
+	LinksAllow  = "allow"  // оставить как есть
+	LinksStrip  = "strip"  // вырезать
+	LinksReject = "reject" // отклонить оправдание
+)
+
+// ValidLinksMode сообщает, известен ли режим Links
+func ValidLinksMode(mode string) bool {
+	return mode == LinksAllow || mode == LinksStrip || mode == LinksReject
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[\p{L}\p{N}._%+-]+@[\p{L}\p{N}-]+(?:\.[\p{L}\p{N}-]+)*\.\p{L}{2,}`)
+	// адрес со схемой, www. или голый домен в популярной зоне
+	urlPattern = regexp.MustCompile(`(?i)\b(?:[a-z][a-z0-9+.-]*://|www\.)[^\s<>"]+` +
+		`|\b[a-z0-9-]+(?:\.[a-z0-9-]+)*\.(?:com|net|org|info|biz|io|me|xyz|ru|su|ua|by|kz|de|uk)\b(?:/[^\s<>"]*)?`)
+)
+
+// Links обрабатывает ссылки и адреса почты согласно Mode.
+type Links struct {
+	Mode string
+}
+
+func (Links) Name() string { return "links" }
+
+func (l Links) Check(c *Content) []Violation {
+	if l.Mode == LinksAllow {
+		return nil
+	}
+
+	// адреса почты ищутся первыми: иначе их домен примут за ссылку
+	withoutEmails := emailPattern.ReplaceAllString(c.Text, " ")
+	hasEmail := withoutEmails != c.Text
+	stripped := urlPattern.ReplaceAllString(withoutEmails, " ")
+	hasURL := stripped != withoutEmails
+
+	if l.Mode == LinksStrip {
+		if hasEmail || hasURL {
+			c.Text = strings.Join(strings.Fields(stripped), " ")
+		}
+		return nil
+	}
+
+	var violations []Violation
+	if hasURL {
+		violations = append(violations, Violation{Code: CodeURLNotAllowed, Message: "Links are not allowed"})
+	}
+	if hasEmail {
+		violations = append(violations, Violation{Code: CodeEmailNotAllowed, Message: "Email addresses are not allowed"})
+	}
+	return violations
+}
+
+// BannedWords запрещает слова из всех списков сразу, независимо от языка
+// оправдания: язык указывает клиент, и выбор списка по нему позволял бы
+// обойти запрет. Слова сравниваются после Fold, поэтому запрет не обходится
+// регистром, буквой ё или подменой букв похожими из другого алфавита.
+// Запись "слово*" запрещает все слова, которые так начинаются.
+type BannedWords struct {
+	list wordList
+}
+
+type wordList struct {
+	exact    map[string]bool
+	prefixes []string
+}
+
+// NewBannedWords строит правило по спискам вида язык -> слова. Языки
+// только группируют слова в настройках, проверяются все списки.
+func NewBannedWords(lists map[string][]string) *BannedWords {
+	b := &BannedWords{list: wordList{exact: make(map[string]bool)}}
+	for _, banned := range lists {
+		for _, word := range banned {
+			word = strings.TrimSpace(word)
+			if prefix := strings.TrimSuffix(word, "*"); prefix != word {
+				b.list.prefixes = append(b.list.prefixes, Fold(prefix))
+				continue
+			}
+			b.list.exact[Fold(word)] = true
+		}
+	}
+	return b
+}
+
+func (*BannedWords) Name() string { return "banned_words" }
+
+func (b *BannedWords) Check(c *Content) []Violation {
+	var violations []Violation
+	seen := make(map[string]bool)
+	for _, word := range words(c.Text) {
+		folded := Fold(word)
+		if seen[folded] || !b.list.match(folded) {
+			continue
+		}
+		seen[folded] = true
+		violations = append(violations, Violation{Code: CodeBannedWord, Message: fmt.Sprintf("Text contains banned word %q", word)})
+	}
+	return violations
+}
+
+func (l wordList) match(word string) bool {
+	if l.exact[word] {
+		return true
+	}
+	for _, prefix := range l.prefixes {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// words делит текст на слова из букв и цифр. Невидимые символы (zero width
+// space и т.п.) выбрасываются заранее, чтобы ими нельзя было разрезать слово.
+func words(text string) []string {
+	text = strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return r
+	}, text)
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
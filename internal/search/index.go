@@ -0,0 +1,126 @@
+package search
+
+import (
+	"math"
+	"sort"
+)
+
+// Параметры BM25
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Match - найденный документ и его релевантность
+type Match struct {
+	ID    string
+	Score float64
+}
+
+// Index - инвертированный индекс: основа слова -> документы с числом
+// вхождений. Не безопасен для конкурентного использования: вызывающий
+// защищает его своей блокировкой.
+type Index struct {
+	postings map[string]map[string]int // основа -> id -> вхождений
+	terms    map[string][]string       // id -> основы документа, для удаления
+	lengths  map[string]int            // id -> число слов без стоп-слов
+	total    int                       // сумма lengths
+}
+
+// NewIndex создает пустой индекс.
+func NewIndex() *Index {
+	return &Index{
+		postings: make(map[string]map[string]int),
+		terms:    make(map[string][]string),
+		lengths:  make(map[string]int),
+	}
+}
+
+// Add индексирует text документа id, заменяя прежний текст.
+func (x *Index) Add(id, text string) {
+	x.Remove(id)
+
+	counts := make(map[string]int)
+	tokens := tokenize(text)
+	for _, tok := range tokens {
+		counts[tok.term]++
+	}
+
+	terms := make([]string, 0, len(counts))
+	for term, n := range counts {
+		docs := x.postings[term]
+		if docs == nil {
+			docs = make(map[string]int)
+			x.postings[term] = docs
+		}
+		docs[id] = n
+		terms = append(terms, term)
+	}
+	x.terms[id] = terms
+	x.lengths[id] = len(tokens)
+	x.total += len(tokens)
+}
+
+// Remove убирает документ id из индекса.
+func (x *Index) Remove(id string) {
+	terms, ok := x.terms[id]
+	if !ok {
+		return
+	}
+	for _, term := range terms {
+		delete(x.postings[term], id)
+		if len(x.postings[term]) == 0 {
+			delete(x.postings, term)
+		}
+	}
+	x.total -= x.lengths[id]
+	delete(x.terms, id)
+	delete(x.lengths, id)
+}
+
+// Search находит документы, содержащие все terms (см. Terms) и
+// прошедшие accept (nil - любые), и возвращает их по убыванию
+// релевантности BM25, при равенстве - по id.
+func (x *Index) Search(terms []string, accept func(id string) bool) []Match {
+	if len(terms) == 0 {
+		return nil
+	}
+
+	// кандидаты - документы самой редкой основы
+	rarest := terms[0]
+	for _, term := range terms[1:] {
+		if len(x.postings[term]) < len(x.postings[rarest]) {
+			rarest = term
+		}
+	}
+
+	docs := float64(len(x.lengths))
+	avgLength := float64(x.total) / docs
+	var matches []Match
+candidates:
+	for id := range x.postings[rarest] {
+		if accept != nil && !accept(id) {
+			continue
+		}
+		score := 0.0
+		for _, term := range terms {
+			tf, ok := x.postings[term][id]
+			if !ok {
+				continue candidates
+			}
+			df := float64(len(x.postings[term]))
+			idf := math.Log(1 + (docs-df+0.5)/(df+0.5))
+			norm := 1 - bm25B + bm25B*float64(x.lengths[id])/avgLength
+			score += idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + bm25K1*norm)
+		}
+		matches = append(matches, Match{ID: id, Score: score})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	return matches
+}
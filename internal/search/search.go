@@ -0,0 +1,124 @@
+// Package search - полнотекстовый поиск по тексту оправданий: разбиение на
+// слова, стемминг (русский и английский snowball, как конфигурация russian
+// в PostgreSQL), инвертированный индекс с ранжированием BM25 и подсветка
+// найденных слов.
+package search
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/kljensen/snowball/english"
+	"github.com/kljensen/snowball/russian"
+)
+
+// Метки подсветки найденных слов; те же передаются в ts_headline
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+// token - слово текста: основа и границы в байтах исходной строки
+type token struct {
+	term       string
+	start, end int
+}
+
+// tokenize разбивает текст на слова и приводит их к основам.
+// Стоп-слова пропускаются.
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		if term := stem(text[start:end]); term != "" {
+			tokens = append(tokens, token{term: term, start: start, end: end})
+		}
+		start = -1
+	}
+
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(text))
+	return tokens
+}
+
+// stem возвращает основу слова или "" для стоп-слова. Кириллица
+// обрабатывается русским стеммером, латиница - английским.
+func stem(word string) string {
+	word = strings.ReplaceAll(strings.ToLower(word), "ё", "е")
+	switch {
+	case hasScript(word, unicode.Cyrillic):
+		if russian.IsStopWord(word) {
+			return ""
+		}
+		return russian.Stem(word, false)
+	case hasScript(word, unicode.Latin):
+		if english.IsStopWord(word) {
+			return ""
+		}
+		return english.Stem(word, false)
+	default:
+		return word
+	}
+}
+
+func hasScript(word string, script *unicode.RangeTable) bool {
+	for _, r := range word {
+		if unicode.Is(script, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Terms возвращает основы слов запроса без стоп-слов и повторов.
+// Пустой результат значит, что искать нечего.
+func Terms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, tok := range tokenize(query) {
+		if !seen[tok.term] {
+			seen[tok.term] = true
+			terms = append(terms, tok.term)
+		}
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// Highlight оборачивает в HighlightStart/HighlightStop слова text,
+// основы которых входят в terms. Сам текст экранируется для HTML, чтобы
+// результат можно было вставить в страницу как есть.
+func Highlight(text string, terms []string) string {
+	wanted := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		wanted[term] = true
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, tok := range tokenize(text) {
+		if !wanted[tok.term] {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[last:tok.start]))
+		b.WriteString(HighlightStart)
+		b.WriteString(html.EscapeString(text[tok.start:tok.end]))
+		b.WriteString(HighlightStop)
+		last = tok.end
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
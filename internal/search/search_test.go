@@ -0,0 +1,90 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTermsStemRussianAndEnglish(t *testing.T) {
+	cases := []struct {
+		a, b string
+	}{
+		{"кот", "кота"},
+		{"клавиатуру", "клавиатура"},
+		{"Ёжик", "ежики"},
+		{"cats", "cat"},
+		{"running", "run"},
+	}
+	for _, tc := range cases {
+		if a, b := Terms(tc.a), Terms(tc.b); !reflect.DeepEqual(a, b) || len(a) != 1 {
+			t.Errorf("Terms(%q) = %v, Terms(%q) = %v; want the same single stem", tc.a, a, tc.b, b)
+		}
+	}
+
+	if terms := Terms("the и, of"); len(terms) != 0 {
+		t.Errorf("stop words only: got %v", terms)
+	}
+	if terms := Terms("cat, CATS cat!"); len(terms) != 1 {
+		t.Errorf("duplicates: got %v", terms)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	got := Highlight("Мой кот сел на клавиатуру, коты такие", Terms("кот"))
+	want := "Мой <mark>кот</mark> сел на клавиатуру, <mark>коты</mark> такие"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got := Highlight("no match here", Terms("cat")); got != "no match here" {
+		t.Errorf("got %q", got)
+	}
+
+	// текст оправдания не должен попасть в разметку как есть
+	got = Highlight(`<script>alert("cat")</script> cats & dogs`, Terms("cat"))
+	want = `&lt;script&gt;alert(&#34;<mark>cat</mark>&#34;)&lt;/script&gt; <mark>cats</mark> &amp; dogs`
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	x := NewIndex()
+	x.Add("a", "The cat ate my homework")
+	x.Add("b", "My cat and my cat's friend, the other cat")
+	x.Add("c", "The dog ate my homework")
+	x.Add("d", "Мой кот ест домашку")
+
+	ids := func(matches []Match) []string {
+		var result []string
+		for _, m := range matches {
+			result = append(result, m.ID)
+		}
+		return result
+	}
+
+	if got := ids(x.Search(Terms("cats"), nil)); !reflect.DeepEqual(got, []string{"b", "a"}) {
+		t.Errorf("cats: got %v, want [b a]", got)
+	}
+	if got := ids(x.Search(Terms("ate homework"), nil)); !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("all terms: got %v, want [a c]", got)
+	}
+	if got := ids(x.Search(Terms("cat dog"), nil)); len(got) != 0 {
+		t.Errorf("every term must match: got %v", got)
+	}
+	if got := ids(x.Search(Terms("кота"), nil)); !reflect.DeepEqual(got, []string{"d"}) {
+		t.Errorf("russian: got %v, want [d]", got)
+	}
+	if got := ids(x.Search(Terms("cat"), func(id string) bool { return id != "b" })); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("accept: got %v, want [a]", got)
+	}
+
+	x.Add("a", "The parrot ate my homework")
+	x.Remove("b")
+	if got := ids(x.Search(Terms("cat"), nil)); len(got) != 0 {
+		t.Errorf("after update and remove: got %v", got)
+	}
+	if got := ids(x.Search(Terms("parrot"), nil)); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("after update: got %v, want [a]", got)
+	}
+}
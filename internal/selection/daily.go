@@ -0,0 +1,117 @@
+package selection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"procrastigo/internal/models"
+	"procrastigo/internal/storage"
+	"sync"
+)
+
+// Daily выбирает "оправдание дня": для одной и той же даты, фильтра и
+// данных результат одинаков у всех клиентов и на всех экземплярах сервиса.
+//
+// Выбор устроен как rendezvous hashing: каждому подходящему оправданию
+// дается оценка hash(date, language, category, id), побеждает максимальная.
+// Поэтому новое оправдание меняет выбор, только если само побеждает, а
+// удаление меняет выбор, только если удален победитель.
+func Daily(ctx context.Context, store storage.Storage, filter storage.ExcuseFilter, date string) (*models.Excuse, error) {
+	candidates, err := collect(ctx, store, filter, storage.SortCreatedAt, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("daily excuse: %w", storage.ErrNotFound)
+	}
+
+	seed := date + "|" + filter.Language + "|" + filter.Category + "|"
+	best, bestScore := 0, uint64(0)
+	for i, excuse := range candidates {
+		score := dailyScore(seed, excuse.ID)
+		if i == 0 || score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return &candidates[best], nil
+}
+
+// DailyCache запоминает победителя Daily для (date, language, category),
+// чтобы не перебирать все оправдания на каждый запрос. Хранятся только
+// записи последней запрошенной даты.
+//
+// Победитель, который удален, снят с публикации или после правки больше не
+// подходит под язык и категорию, выбирается заново (и rendezvous hashing
+// дает тот же результат, что и без кеша). Оправдание, добавленное после
+// выбора, может стать оправданием дня не раньше следующего дня, поэтому до
+// полуночи экземпляры, заполнившие кеш до и после его добавления, могут
+// отдавать разных победителей.
+type DailyCache struct {
+	store storage.Storage
+
+	mu      sync.Mutex
+	date    string
+	winners map[dailyKey]string // id победителя
+}
+
+type dailyKey struct {
+	language, category string
+}
+
+// NewDailyCache создает пустой кеш поверх store.
+func NewDailyCache(store storage.Storage) *DailyCache {
+	return &DailyCache{store: store, winners: make(map[dailyKey]string)}
+}
+
+// Get возвращает оправдание дня среди одобренных оправданий языка
+// language и категории category (пустая - любая) на дату date.
+func (c *DailyCache) Get(ctx context.Context, language, category, date string) (*models.Excuse, error) {
+	key := dailyKey{language, category}
+	filter := storage.ExcuseFilter{Language: language, Category: category, Status: models.StatusApproved}
+	if id, ok := c.lookup(date, key); ok {
+		excuse, err := c.store.GetExcuse(ctx, id)
+		if err == nil && filter.Matches(*excuse) {
+			// рейтинг и текст - свежие, из хранилища
+			return excuse, nil
+		}
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	excuse, err := Daily(ctx, c.store, filter, date)
+	if err != nil {
+		return nil, err
+	}
+	c.remember(date, key, excuse.ID)
+	return excuse, nil
+}
+
+func (c *DailyCache) lookup(date string, key dailyKey) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if date != c.date {
+		return "", false
+	}
+	id, ok := c.winners[key]
+	return id, ok
+}
+
+func (c *DailyCache) remember(date string, key dailyKey, id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if date != c.date {
+		// наступил новый день: вчерашние победители больше не нужны
+		c.date = date
+		c.winners = make(map[dailyKey]string)
+	}
+	c.winners[key] = id
+}
+
+func dailyScore(seed, id string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(seed))
+	h.Write([]byte(id))
+	return h.Sum64()
+}
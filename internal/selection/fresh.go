@@ -0,0 +1,112 @@
+package selection
+
+import (
+	"context"
+	"errors"
+	"procrastigo/internal/models"
+	"procrastigo/internal/storage"
+	"sync"
+)
+
+// Fresh выбирает случайное оправдание, которое клиент не видел среди
+// последних показов. Если непоказанных не осталось, выбирает из всех.
+type Fresh struct {
+	Store   storage.Storage
+	History *History
+}
+
+func (s Fresh) Select(ctx context.Context, filter storage.ExcuseFilter, clientID string) (*models.Excuse, error) {
+	recent := s.History.Recent(clientID)
+	if len(recent) == 0 {
+		return s.Store.GetRandomExcuse(ctx, filter)
+	}
+
+	fresh := filter
+	fresh.ExcludeIDs = append(append([]string(nil), filter.ExcludeIDs...), recent...)
+
+	excuse, err := s.Store.GetRandomExcuse(ctx, fresh)
+	if errors.Is(err, storage.ErrNotFound) {
+		return s.Store.GetRandomExcuse(ctx, filter)
+	}
+	return excuse, err
+}
+
+// History помнит последние size показанных id для не более чем maxClients
+// клиентов. При переполнении забывается клиент, который дольше всех не
+// обращался.
+type History struct {
+	size       int
+	maxClients int
+
+	mu      sync.Mutex
+	clients map[string]*clientHistory
+	clock   uint64 // счетчик обращений для выбора давно неактивного клиента
+}
+
+type clientHistory struct {
+	ids      []string // от старых к новым
+	lastUsed uint64
+}
+
+func NewHistory(size, maxClients int) *History {
+	return &History{
+		size:       size,
+		maxClients: maxClients,
+		clients:    make(map[string]*clientHistory),
+	}
+}
+
+// Add запоминает показ id клиенту.
+func (h *History) Add(clientID, id string) {
+	if h.size <= 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	client, ok := h.clients[clientID]
+	if !ok {
+		if h.maxClients > 0 && len(h.clients) >= h.maxClients {
+			h.evictOldest()
+		}
+		client = &clientHistory{}
+		h.clients[clientID] = client
+	}
+
+	h.clock++
+	client.lastUsed = h.clock
+	for i, seen := range client.ids {
+		if seen == id {
+			client.ids = append(client.ids[:i], client.ids[i+1:]...)
+			break
+		}
+	}
+	client.ids = append(client.ids, id)
+	if len(client.ids) > h.size {
+		client.ids = client.ids[len(client.ids)-h.size:]
+	}
+}
+
+// Recent возвращает копию последних показанных клиенту id.
+func (h *History) Recent(clientID string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	client, ok := h.clients[clientID]
+	if !ok {
+		return nil
+	}
+	return append([]string(nil), client.ids...)
+}
+
+func (h *History) evictOldest() {
+	var oldestID string
+	var oldest uint64
+	for id, client := range h.clients {
+		if oldestID == "" || client.lastUsed < oldest {
+			oldestID, oldest = id, client.lastUsed
+		}
+	}
+	delete(h.clients, oldestID)
+}
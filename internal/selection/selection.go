@@ -0,0 +1,83 @@
+// Package selection выбирает оправдания для выдачи клиентам по разным
+// стратегиям поверх любого storage.Storage.
+package selection
+
+import (
+	"context"
+	"procrastigo/internal/models"
+	"procrastigo/internal/storage"
+)
+
+// Режимы выбора случайного оправдания
+const (
+	ModeUniform  = "uniform"  // равновероятно среди подходящих
+	ModeWeighted = "weighted" // вероятность растет с рейтингом
+	ModeFresh    = "fresh"    // без недавно показанных этому клиенту
+)
+
+// Strategy выбирает одно оправдание из подходящих под фильтр.
+// Если подходящих нет, возвращается ошибка storage.ErrNotFound.
+type Strategy interface {
+	Select(ctx context.Context, filter storage.ExcuseFilter, clientID string) (*models.Excuse, error)
+}
+
+// Options - настройки стратегий
+type Options struct {
+	WeightFloor   float64 // минимальный вес в режиме weighted
+	MaxCandidates int     // сколько лучших по рейтингу оправданий рассматривает weighted
+	HistorySize   int     // сколько последних показов помнит fresh для клиента
+	MaxClients    int     // сколько клиентов помнит история
+}
+
+// Selector хранит стратегии по режимам и запоминает, что было показано
+// каждому клиенту, чтобы режим fresh работал после любого режима.
+type Selector struct {
+	strategies map[string]Strategy
+	history    *History
+}
+
+// New создает Selector со всеми встроенными стратегиями.
+func New(store storage.Storage, opts Options) *Selector {
+	history := NewHistory(opts.HistorySize, opts.MaxClients)
+	return &Selector{
+		strategies: map[string]Strategy{
+			ModeUniform:  Uniform{Store: store},
+			ModeWeighted: Weighted{Store: store, Floor: opts.WeightFloor, MaxCandidates: opts.MaxCandidates},
+			ModeFresh:    Fresh{Store: store, History: history},
+		},
+		history: history,
+	}
+}
+
+// ValidMode сообщает, есть ли стратегия для режима.
+func (s *Selector) ValidMode(mode string) bool {
+	_, ok := s.strategies[mode]
+	return ok
+}
+
+// Select выбирает оправдание стратегией mode и запоминает показ клиенту.
+func (s *Selector) Select(ctx context.Context, mode string, filter storage.ExcuseFilter, clientID string) (*models.Excuse, error) {
+	strategy, ok := s.strategies[mode]
+	if !ok {
+		return nil, storage.Invalidf("unknown mode %q", mode)
+	}
+
+	excuse, err := strategy.Select(ctx, filter, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if clientID != "" {
+		s.history.Add(clientID, excuse.ID)
+	}
+	return excuse, nil
+}
+
+// Uniform выбирает равновероятно средствами хранилища.
+type Uniform struct {
+	Store storage.Storage
+}
+
+func (u Uniform) Select(ctx context.Context, filter storage.ExcuseFilter, _ string) (*models.Excuse, error) {
+	return u.Store.GetRandomExcuse(ctx, filter)
+}
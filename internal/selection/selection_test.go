@@ -0,0 +1,237 @@
+package selection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"procrastigo/internal/models"
+	"procrastigo/internal/storage"
+	"testing"
+	"time"
+)
+
+func newStore(t *testing.T, ratings ...int) storage.Storage {
+	t.Helper()
+	store := storage.NewMemoryStorage()
+	for i, rating := range ratings {
+		err := store.CreateExcuse(context.Background(), models.Excuse{
+			ID: fmt.Sprintf("s%d", i), Text: "text", Category: "work", Language: "en",
+			Severity: "low", CreatedAt: time.Now(), Rating: rating,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func TestFreshAvoidsRecentExcuses(t *testing.T) {
+	selector := New(newStore(t, 0, 0, 0, 0), Options{HistorySize: 3, MaxClients: 10})
+	ctx := context.Background()
+
+	var served []string
+	for i := 0; i < 8; i++ {
+		excuse, err := selector.Select(ctx, ModeFresh, storage.ExcuseFilter{}, "client")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range served[max(0, len(served)-3):] {
+			if id == excuse.ID {
+				t.Fatalf("draw %d: %s was served within the last 3 (%v)", i, excuse.ID, served)
+			}
+		}
+		served = append(served, excuse.ID)
+	}
+}
+
+func TestFreshFallsBackWhenEverythingWasSeen(t *testing.T) {
+	selector := New(newStore(t, 0), Options{HistorySize: 5, MaxClients: 10})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := selector.Select(ctx, ModeFresh, storage.ExcuseFilter{}, "client"); err != nil {
+			t.Fatalf("draw %d: %v", i, err)
+		}
+	}
+}
+
+func TestWeightedPrefersHighRating(t *testing.T) {
+	// веса: s0 -> 0.5 (пол), s1 -> 1, s2 -> 99
+	selector := New(newStore(t, -50, 0, 98), Options{WeightFloor: 0.5, MaxCandidates: 100})
+	ctx := context.Background()
+
+	counts := make(map[string]int)
+	for i := 0; i < 2000; i++ {
+		excuse, err := selector.Select(ctx, ModeWeighted, storage.ExcuseFilter{}, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[excuse.ID]++
+	}
+	if counts["s2"] < 1800 {
+		t.Fatalf("favourite served %d of 2000 times, want about 98%%: %v", counts["s2"], counts)
+	}
+}
+
+func TestUnknownModeAndEmptyResult(t *testing.T) {
+	selector := New(newStore(t), Options{WeightFloor: 1, MaxCandidates: 10})
+	ctx := context.Background()
+
+	if _, err := selector.Select(ctx, "bogus", storage.ExcuseFilter{}, ""); !errors.Is(err, storage.ErrInvalid) {
+		t.Fatalf("unknown mode: got %v, want ErrInvalid", err)
+	}
+	for _, mode := range []string{ModeUniform, ModeWeighted, ModeFresh} {
+		if _, err := selector.Select(ctx, mode, storage.ExcuseFilter{}, "c"); !errors.Is(err, storage.ErrNotFound) {
+			t.Fatalf("%s on empty storage: got %v, want ErrNotFound", mode, err)
+		}
+	}
+}
+
+func TestHistoryEvictsLeastRecentClient(t *testing.T) {
+	history := NewHistory(2, 2)
+	history.Add("a", "1")
+	history.Add("b", "1")
+	history.Add("a", "2")
+	history.Add("c", "1") // вытесняет b
+
+	if got := history.Recent("b"); got != nil {
+		t.Fatalf("b should be evicted, got %v", got)
+	}
+	if got := history.Recent("a"); len(got) != 2 || got[0] != "1" || got[1] != "2" {
+		t.Fatalf("a history: %v", got)
+	}
+}
+
+func TestDailyIsStableForADate(t *testing.T) {
+	store := newStore(t, 0, 0, 0, 0, 0, 0, 0, 0)
+	ctx := context.Background()
+
+	first, err := Daily(ctx, store, storage.ExcuseFilter{Language: "en"}, "2024-03-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		again, err := Daily(ctx, store, storage.ExcuseFilter{Language: "en"}, "2024-03-01")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again.ID != first.ID {
+			t.Fatalf("daily changed within a day: %s then %s", first.ID, again.ID)
+		}
+	}
+
+	// удаление проигравшего не меняет выбор
+	for i := 0; i < 8; i++ {
+		id := fmt.Sprintf("s%d", i)
+		if id != first.ID {
+			if err := store.DeleteExcuse(ctx, id); err != nil {
+				t.Fatal(err)
+			}
+			break
+		}
+	}
+	again, err := Daily(ctx, store, storage.ExcuseFilter{Language: "en"}, "2024-03-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != first.ID {
+		t.Fatalf("deleting another excuse changed the daily pick: %s then %s", first.ID, again.ID)
+	}
+
+	days := make(map[string]bool)
+	for d := 1; d <= 10; d++ {
+		excuse, err := Daily(ctx, store, storage.ExcuseFilter{}, fmt.Sprintf("2024-03-%02d", d))
+		if err != nil {
+			t.Fatal(err)
+		}
+		days[excuse.ID] = true
+	}
+	if len(days) < 2 {
+		t.Fatalf("10 different days picked the same excuse: %v", days)
+	}
+
+	if _, err := Daily(ctx, store, storage.ExcuseFilter{Language: "de"}, "2024-03-01"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("no candidates: got %v, want ErrNotFound", err)
+	}
+}
+
+// countingStore считает обращения к GetExcuses
+type countingStore struct {
+	storage.Storage
+	lists int
+}
+
+func (s *countingStore) GetExcuses(ctx context.Context, filter storage.ExcuseFilter, page storage.PageRequest) (*models.ExcusePage, error) {
+	s.lists++
+	return s.Storage.GetExcuses(ctx, filter, page)
+}
+
+func TestDailyCacheScansOncePerDay(t *testing.T) {
+	store := &countingStore{Storage: newStore(t, 0, 0, 0, 0, 0, 0, 0, 0)}
+	cache := NewDailyCache(store)
+	ctx := context.Background()
+
+	want, err := Daily(ctx, store, storage.ExcuseFilter{Language: "en", Status: models.StatusApproved}, "2024-03-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.lists = 0
+
+	for i := 0; i < 5; i++ {
+		got, err := cache.Get(ctx, "en", "", "2024-03-01")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != want.ID {
+			t.Fatalf("cached daily %s, want %s", got.ID, want.ID)
+		}
+	}
+	if store.lists != 1 {
+		t.Fatalf("5 requests scanned the store %d times, want 1", store.lists)
+	}
+
+	// удаленный победитель выбирается заново, как без кеша
+	if err := store.DeleteExcuse(ctx, want.ID); err != nil {
+		t.Fatal(err)
+	}
+	got, err := cache.Get(ctx, "en", "", "2024-03-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := Daily(ctx, store, storage.ExcuseFilter{Language: "en", Status: models.StatusApproved}, "2024-03-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID == want.ID || got.ID != next.ID {
+		t.Fatalf("after deleting %s: cached %s, want %s", want.ID, got.ID, next.ID)
+	}
+
+	// победитель, переведенный на другой язык, тоже выбирается заново
+	moved := *next
+	moved.Language = "ru"
+	if _, err := store.UpdateExcuse(ctx, moved); err != nil {
+		t.Fatal(err)
+	}
+	got, err = cache.Get(ctx, "en", "", "2024-03-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID == moved.ID || got.Language != "en" {
+		t.Fatalf("after moving %s to ru: cached %+v", moved.ID, got)
+	}
+
+	// другая дата и другая категория - отдельные записи
+	store.lists = 0
+	if _, err := cache.Get(ctx, "en", "", "2024-03-02"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Get(ctx, "en", "work", "2024-03-02"); err != nil {
+		t.Fatal(err)
+	}
+	if store.lists != 2 {
+		t.Fatalf("new date and category: %d scans, want 2", store.lists)
+	}
+	if _, err := cache.Get(ctx, "de", "", "2024-03-02"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("no candidates: got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,79 @@
+package selection
+
+import (
+	"context"
+	"fmt"
+	"procrastigo/internal/models"
+	"procrastigo/internal/storage"
+	"procrastigo/pkg/utils"
+)
+
+// Weighted выбирает оправдание с вероятностью, пропорциональной
+// max(rating+1, Floor): оправдание с нулевым рейтингом имеет вес 1,
+// а сильно заминусованные не пропадают совсем, сохраняя вес Floor.
+// Рассматриваются только MaxCandidates лучших по рейтингу.
+type Weighted struct {
+	Store         storage.Storage
+	Floor         float64
+	MaxCandidates int
+}
+
+func (s Weighted) Select(ctx context.Context, filter storage.ExcuseFilter, _ string) (*models.Excuse, error) {
+	candidates, err := collect(ctx, s.Store, filter, storage.SortRating, s.MaxCandidates)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("weighted excuse: %w", storage.ErrNotFound)
+	}
+
+	weights := make([]float64, len(candidates))
+	total := 0.0
+	for i, excuse := range candidates {
+		weights[i] = s.weight(excuse)
+		total += weights[i]
+	}
+
+	point := utils.RandomFloat() * total
+	for i, w := range weights {
+		if point < w {
+			return &candidates[i], nil
+		}
+		point -= w
+	}
+	// защита от погрешности округления
+	return &candidates[len(candidates)-1], nil
+}
+
+func (s Weighted) weight(excuse models.Excuse) float64 {
+	w := float64(excuse.Rating) + 1
+	if w < s.Floor {
+		return s.Floor
+	}
+	return w
+}
+
+// collect постранично читает до max подходящих оправданий в порядке sortBy
+// (все подходящие, если max <= 0).
+func collect(ctx context.Context, store storage.Storage, filter storage.ExcuseFilter, sortBy string, max int) ([]models.Excuse, error) {
+	const pageSize = 200
+
+	var result []models.Excuse
+	page := storage.PageRequest{Sort: sortBy, Limit: pageSize}
+	for {
+		if max > 0 && max-len(result) < page.Limit {
+			page.Limit = max - len(result)
+		}
+
+		chunk, err := store.GetExcuses(ctx, filter, page)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, chunk.Excuses...)
+
+		if chunk.NextCursor == "" || (max > 0 && len(result) >= max) {
+			return result, nil
+		}
+		page.Cursor = chunk.NextCursor
+	}
+}
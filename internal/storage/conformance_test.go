@@ -0,0 +1,80 @@
+package storage_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"procrastigo/internal/storage"
+	"procrastigo/internal/storage/storagetest"
+	"procrastigo/pkg/logger"
+	"testing"
+)
+
+// postgresTestDSN - переменная окружения с DSN тестовой базы PostgreSQL.
+// Тесты очищают таблицу excuses, поэтому не указывайте рабочую базу.
+const postgresTestDSN = "PROCRASTIGO_TEST_POSTGRES_DSN"
+
+// TestConformance прогоняет общий набор тестов через каждый бэкенд:
+// MemoryStorage (в памяти и с журналом на диске) и SQLiteStorage всегда,
+// PostgresStorage при заданном DSN.
+func TestConformance(t *testing.T) {
+	logger.Init("production")
+
+	t.Run("memory", func(t *testing.T) {
+		storagetest.Run(t, func(t *testing.T) storage.Storage {
+			return storage.NewMemoryStorage()
+		})
+	})
+
+	t.Run("memory_persistent", func(t *testing.T) {
+		storagetest.Run(t, func(t *testing.T) storage.Storage {
+			store, err := storage.OpenMemoryStorage(storage.PersistOptions{Dir: t.TempDir(), Fsync: storage.FsyncNever})
+			if err != nil {
+				t.Fatalf("OpenMemoryStorage: %v", err)
+			}
+			return store
+		})
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		storagetest.Run(t, func(t *testing.T) storage.Storage {
+			path := filepath.Join(t.TempDir(), "test.db")
+			store, err := storage.NewSQLiteStorage(context.Background(), path, true)
+			if err != nil {
+				t.Fatalf("NewSQLiteStorage: %v", err)
+			}
+			return store
+		})
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		dsn := os.Getenv(postgresTestDSN)
+		if dsn == "" {
+			t.Skipf("%s is not set", postgresTestDSN)
+		}
+
+		storagetest.Run(t, func(t *testing.T) storage.Storage {
+			ctx := context.Background()
+			store, err := storage.NewPostgresStorage(ctx, dsn, true)
+			if err != nil {
+				t.Fatalf("NewPostgresStorage: %v", err)
+			}
+			truncate(t, dsn)
+			return store
+		})
+	})
+}
+
+// truncate очищает тестовую базу PostgreSQL перед очередным тестом
+func truncate(t *testing.T, dsn string) {
+	t.Helper()
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("TRUNCATE excuses CASCADE"); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+}
@@ -1,211 +1,282 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"procrastigo/internal/dedup"
 	"procrastigo/internal/models"
-	"procrastigo/pkg/utils"
-	"strings"
-	_ "time"
+	"procrastigo/internal/search"
+	"procrastigo/internal/storage/migrations"
+	"procrastigo/pkg/logger"
+	"strconv"
 
 	_ "github.com/lib/pq"
 )
 
 // PostgresStorage реализует Storage с использованием PostgreSQL
 type PostgresStorage struct {
-	db *sql.DB
+	sqlStorage
 }
 
+// postgresDialect - диалект PostgreSQL; драйвер понимает аргументы как есть
+type postgresDialect struct{}
+
+func (postgresDialect) name() string                   { return migrations.DialectPostgres }
+func (postgresDialect) bind(v interface{}) interface{} { return v }
+func (postgresDialect) lockRow() string                { return " FOR UPDATE" }
+
+// byteOrder: collation по умолчанию (например, en_US.UTF-8) при первом
+// сравнении игнорирует пунктуацию, поэтому id сравниваются в "C"
+func (postgresDialect) byteOrder(column string) string { return column + ` COLLATE "C"` }
+
 // NewPostgresStorage создает новое хранилище PostgreSQL и проверяет подключение.
-func NewPostgresStorage(dsn string) (*PostgresStorage, error) {
-	db, err := sql.Open("postgres", dsn)
+// Если autoMigrate включен, ожидающие миграции применяются сразу;
+// иначе о них только выводится предупреждение.
+func NewPostgresStorage(ctx context.Context, dsn string, autoMigrate bool) (*PostgresStorage, error) {
+	db, err := openPostgres(ctx, dsn)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database connection: %w", err)
-	}
-
-	if err = db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+		return nil, err
 	}
 
-	// Установка миграций/создание таблицы
-	if err := createExcusesTable(db); err != nil {
-		return nil, fmt.Errorf("failed to create excuses table: %w", err)
+	dialect := postgresDialect{}
+	if err := prepareSchema(ctx, db, dialect, autoMigrate); err != nil {
+		db.Close()
+		return nil, err
 	}
 
-	return &PostgresStorage{db: db}, nil
+	return &PostgresStorage{sqlStorage{db: db, dialect: dialect}}, nil
 }
 
-// createExcusesTable создает таблицу excuses, если она не существует
-func createExcusesTable(db *sql.DB) error {
-	query := `
-    CREATE TABLE IF NOT EXISTS excuses (
-        id VARCHAR(50) PRIMARY KEY,
-        text TEXT NOT NULL,
-        category VARCHAR(50) NOT NULL,
-        language VARCHAR(10) NOT NULL,
-        severity VARCHAR(20) NOT NULL,
-        created_at TIMESTAMP WITH TIME ZONE NOT NULL,
-        rating INTEGER DEFAULT 0
-    );`
-	_, err := db.Exec(query)
-	return err
-}
-
-// LoadFromFile в PostgresStorage не используется, но должен быть реализован для интерфейса.
-func (s *PostgresStorage) LoadFromFile(filename string) error {
-	// В реальном приложении здесь может быть логика импорта данных
-	return nil
-}
-
-// GetRandomExcuse получает случайное оправдание из БД
-func (s *PostgresStorage) GetRandomExcuse() (*models.Excuse, error) {
-	var excuse models.Excuse
-	query := `
-    SELECT id, text, category, language, severity, created_at, rating
-    FROM excuses
-    ORDER BY RANDOM()
-    LIMIT 1`
-
-	row := s.db.QueryRow(query)
-	// Обязательно сканируем все поля, включая Rating
-	err := row.Scan(&excuse.ID, &excuse.Text, &excuse.Category, &excuse.Language, &excuse.Severity, &excuse.CreatedAt, &excuse.Rating)
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
+// openPostgres открывает пул соединений и проверяет, что база доступна
+func openPostgres(ctx context.Context, dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
 	if err != nil {
-		return nil, fmt.Errorf("failed to scan random excuse: %w", err)
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	}
+
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w: %v", ErrUnavailable, err)
 	}
 
-	return &excuse, nil
+	return db, nil
 }
 
-// GetExcuses получает список оправданий с фильтрацией и сортировкой по рейтингу
-func (s *PostgresStorage) GetExcuses(category, language string, limit int) ([]models.Excuse, error) {
-	var args []interface{}
-	argCounter := 1
+// prepareSchema применяет миграции или проверяет, что их не осталось
+func prepareSchema(ctx context.Context, db *sql.DB, dialect sqlDialect, autoMigrate bool) error {
+	migrator, err := migrations.New(db, dialect.name())
+	if err != nil {
+		return err
+	}
 
-	// Динамическое построение WHERE части запроса
-	whereClauses := []string{}
+	if autoMigrate {
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to apply migrations: %w", err)
+		}
+		if applied > 0 {
+			logger.Info.Printf("Applied %d database migration(s)", applied)
+		}
+		return nil
+	}
 
-	if category != "" {
-		whereClauses = append(whereClauses, fmt.Sprintf("category = $%d", argCounter))
-		args = append(args, category)
-		argCounter++
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check migrations: %w", err)
 	}
-	if language != "" {
-		whereClauses = append(whereClauses, fmt.Sprintf("language = $%d", argCounter))
-		args = append(args, language)
-		argCounter++
+	if pending > 0 {
+		logger.Warn.Printf("%d database migration(s) pending; run `procrastigo migrate up`", pending)
 	}
+	return nil
+}
 
-	// Собираем запрос
-	sqlQuery := "SELECT id, text, category, language, severity, created_at, rating FROM excuses"
-	if len(whereClauses) > 0 {
-		sqlQuery += " WHERE " + strings.Join(whereClauses, " AND ")
+// htmlEscapeSQL экранирует column для HTML теми же сущностями, что и
+// html.EscapeString
+func htmlEscapeSQL(column string) string {
+	for _, r := range [][2]string{{"&", "&amp;"}, {"<", "&lt;"}, {">", "&gt;"}, {"''", "&#39;"}, {`"`, "&#34;"}} {
+		column = fmt.Sprintf("replace(%s, '%s', '%s')", column, r[0], r[1])
 	}
+	return column
+}
 
-	// Сортируем по рейтингу и ограничиваем
-	sqlQuery += fmt.Sprintf(" ORDER BY rating DESC, created_at DESC LIMIT $%d", argCounter)
-	args = append(args, limit)
+// SearchExcuses ищет по колонке search_vector (GIN индекс). Запрос
+// разбирается той же конфигурацией russian, что и текст, все слова
+// обязательны; подсветку строит ts_headline по тексту, экранированному для
+// HTML так же, как в search.Highlight.
+func (s *PostgresStorage) SearchExcuses(ctx context.Context, query SearchQuery) ([]models.SearchHit, error) {
+	if _, err := query.terms(); err != nil {
+		return nil, err
+	}
 
-	rows, err := s.db.Query(sqlQuery, args...)
+	where, args := query.Filter.sqlWhere(2)
+	if where != "" {
+		where = " AND " + where
+	}
+	args = append([]interface{}{query.Text}, args...)
+	args = append(args, query.limit())
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+    SELECT %s, ts_rank(search_vector, q) AS score,
+        ts_headline('russian', %s, q, 'StartSel="%s", StopSel="%s", HighlightAll=true')
+    FROM excuses, plainto_tsquery('russian', $1) AS q
+    WHERE search_vector @@ q%s
+    ORDER BY score DESC, id COLLATE "C"
+    LIMIT $%d`, excuseColumns, htmlEscapeSQL("text"), search.HighlightStart, search.HighlightStop, where, len(args)), s.args(args...)...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query excuses: %w", err)
+		return nil, fmt.Errorf("failed to search excuses: %w", dbErr(ctx, err))
 	}
 	defer rows.Close()
 
-	var excuses []models.Excuse
+	hits := []models.SearchHit{}
 	for rows.Next() {
-		var excuse models.Excuse
-		// Обязательно сканируем все поля, включая Rating
-		if err := rows.Scan(&excuse.ID, &excuse.Text, &excuse.Category, &excuse.Language, &excuse.Severity, &excuse.CreatedAt, &excuse.Rating); err != nil {
-			return nil, fmt.Errorf("failed to scan excuse row: %w", err)
+		var hit models.SearchHit
+		var err error
+		if hit.Excuse, err = scanExcuse(rows, &hit.Score, &hit.Highlight); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", dbErr(ctx, err))
 		}
-		excuses = append(excuses, excuse)
+		hits = append(hits, hit)
 	}
-
-	return excuses, nil
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate search results: %w", dbErr(ctx, err))
+	}
+	return hits, nil
 }
 
-// CreateExcuse создает новое оправдание
-func (s *PostgresStorage) CreateExcuse(excuse models.Excuse) error {
-	query := `
-    INSERT INTO excuses (id, text, category, language, severity, created_at, rating)
-    VALUES ($1, $2, $3, $4, $5, $6, $7)`
-
-	_, err := s.db.Exec(query,
-		excuse.ID,
-		excuse.Text,
-		excuse.Category,
-		excuse.Language,
-		excuse.Severity,
-		excuse.CreatedAt,
-		excuse.Rating, // <--- Вставляем рейтинг
-	)
+// FindSimilar ищет по GIN индексу pg_trgm. Порог передается оператору %
+// через pg_trgm.similarity_threshold в пределах транзакции.
+func (s *PostgresStorage) FindSimilar(ctx context.Context, text string, threshold float64, limit int) ([]models.SimilarExcuse, error) {
+	if err := checkThreshold(threshold); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + excuseColumns + `, similarity(text, $1) AS sim
+    FROM excuses
+    WHERE text % $1 AND status = $2
+    ORDER BY sim DESC, id COLLATE "C"`
+	args := []interface{}{text, models.StatusApproved}
+	if limit > 0 {
+		query += " LIMIT $3"
+		args = append(args, limit)
+	}
+
+	result := []models.SimilarExcuse{}
+	err := s.withTrigramThreshold(ctx, threshold, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var similar models.SimilarExcuse
+			var err error
+			if similar.Excuse, err = scanExcuse(rows, &similar.Similarity); err != nil {
+				return err
+			}
+			result = append(result, similar)
+		}
+		return rows.Err()
+	})
 	if err != nil {
-		return fmt.Errorf("failed to insert excuse: %w", err)
+		return nil, fmt.Errorf("failed to find similar excuses: %w", dbErr(ctx, err))
 	}
-	return nil
+	return result, nil
 }
 
-// RateExcuse обновляет рейтинг оправдания
-func (s *PostgresStorage) RateExcuse(id string, change int) error {
-	query := `
-    UPDATE excuses
-    SET rating = rating + $1
-    WHERE id = $2`
+// DuplicateClusters находит похожие пары самосоединением по оператору %
+func (s *PostgresStorage) DuplicateClusters(ctx context.Context, threshold float64) ([]models.DuplicateCluster, error) {
+	if err := checkThreshold(threshold); err != nil {
+		return nil, err
+	}
 
-	res, err := s.db.Exec(query, change, id)
+	var pairs []dedup.Pair
+	err := s.withTrigramThreshold(ctx, threshold, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+    SELECT a.id, b.id, similarity(a.text, b.text)
+    FROM excuses a JOIN excuses b ON a.id < b.id AND a.text % b.text`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var pair dedup.Pair
+			if err := rows.Scan(&pair.A, &pair.B, &pair.Similarity); err != nil {
+				return err
+			}
+			pairs = append(pairs, pair)
+		}
+		return rows.Err()
+	})
 	if err != nil {
-		return fmt.Errorf("failed to update excuse rating: %w", err)
+		return nil, fmt.Errorf("failed to find duplicate pairs: %w", dbErr(ctx, err))
 	}
 
-	rowsAffected, err := res.RowsAffected()
+	byID, err := s.excusesByID(ctx, pairs)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return nil, err
 	}
+	return duplicateClusters(pairs, func(id string) models.Excuse { return byID[id] }), nil
+}
 
-	if rowsAffected == 0 {
-		return sql.ErrNoRows // Оправдание не найдено
+// withTrigramThreshold выполняет fn в транзакции только для чтения
+// с заданным порогом оператора %
+func (s *PostgresStorage) withTrigramThreshold(ctx context.Context, threshold float64, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	return nil
+	if _, err := tx.ExecContext(ctx, "SELECT set_config('pg_trgm.similarity_threshold', $1, true)",
+		strconv.FormatFloat(threshold, 'f', -1, 64)); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// GetStats вычисляет и возвращает статистику
-func (s *PostgresStorage) GetStats() (*models.Stats, error) {
-	stats := &models.Stats{}
-
-	// 1. Общее количество
-	if err := s.db.QueryRow("SELECT COUNT(*) FROM excuses").Scan(&stats.TotalExcuses); err != nil {
-		return nil, fmt.Errorf("failed to get total excuses: %w", err)
+// excusesByID читает оправдания, упомянутые в парах
+func (s *PostgresStorage) excusesByID(ctx context.Context, pairs []dedup.Pair) (map[string]models.Excuse, error) {
+	byID := make(map[string]models.Excuse)
+	if len(pairs) == 0 {
+		return byID, nil
 	}
 
-	// 2. Самая популярная категория (используем COALESCE для случая, когда нет данных)
-	var mostPopular sql.NullString
-	err := s.db.QueryRow(`
-    SELECT category FROM excuses
-    GROUP BY category
-    ORDER BY COUNT(*) DESC
-    LIMIT 1`).Scan(&mostPopular)
-
-	if err != nil && err != sql.ErrNoRows {
-		return nil, fmt.Errorf("failed to get most popular category: %w", err)
+	var ids []string
+	seen := make(map[string]bool)
+	for _, pair := range pairs {
+		for _, id := range []string{pair.A, pair.B} {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
 	}
-	stats.MostPopularCategory = mostPopular.String
-
-	// 3. Оправдания за сегодня
-	today := utils.GetStartOfDay()
-	if err := s.db.QueryRow(`
-    SELECT COUNT(*) FROM excuses
-    WHERE created_at >= $1`, today).Scan(&stats.ExcusesToday); err != nil {
-		return nil, fmt.Errorf("failed to get excuses today: %w", err)
+	placeholders := make([]interface{}, len(ids))
+	for i := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
 	}
 
-	// 4. Уровень прокрастинации
-	stats.GlobalProcrastinationLevel = utils.CalculateProcrastinationLevel(stats.ExcusesToday)
-
-	return stats, nil
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+excuseColumns+" FROM excuses WHERE id IN ("+fmt.Sprintf(listPlaceholders(len(ids)), placeholders...)+")",
+		stringArgs(ids)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query excuses: %w", dbErr(ctx, err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		excuse, err := scanExcuse(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan excuse row: %w", dbErr(ctx, err))
+		}
+		byID[excuse.ID] = excuse
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate excuses: %w", dbErr(ctx, err))
+	}
+	return byID, nil
 }
 
 var _ Storage = (*PostgresStorage)(nil)
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"procrastigo/internal/dedup"
+	"procrastigo/internal/models"
+	"sort"
+)
+
+// checkThreshold проверяет порог сходства
+func checkThreshold(threshold float64) error {
+	if threshold <= 0 || threshold > 1 {
+		return Invalidf("similarity threshold must be in (0, 1], got %g", threshold)
+	}
+	return nil
+}
+
+// similarExcuses сравнивает text с каждым из excuses и возвращает до
+// limit самых похожих (при равенстве - по id)
+func similarExcuses(text string, threshold float64, limit int, excuses []models.Excuse, trigrams func(models.Excuse) dedup.Trigrams) []models.SimilarExcuse {
+	target := dedup.NewTrigrams(text)
+	result := []models.SimilarExcuse{}
+	for _, excuse := range excuses {
+		if excuse.Status != models.StatusApproved {
+			continue
+		}
+		if similarity := dedup.Similarity(target, trigrams(excuse)); similarity >= threshold {
+			result = append(result, models.SimilarExcuse{Excuse: excuse, Similarity: similarity})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Similarity != result[j].Similarity {
+			return result[i].Similarity > result[j].Similarity
+		}
+		return result[i].Excuse.ID < result[j].Excuse.ID
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// similarPairs сравнивает все оправдания попарно
+func similarPairs(excuses []models.Excuse, threshold float64, trigrams func(models.Excuse) dedup.Trigrams) []dedup.Pair {
+	sets := make([]dedup.Trigrams, len(excuses))
+	for i, excuse := range excuses {
+		sets[i] = trigrams(excuse)
+	}
+	var pairs []dedup.Pair
+	for i := range excuses {
+		for j := i + 1; j < len(excuses); j++ {
+			if similarity := dedup.Similarity(sets[i], sets[j]); similarity >= threshold {
+				pairs = append(pairs, dedup.Pair{A: excuses[i].ID, B: excuses[j].ID, Similarity: similarity})
+			}
+		}
+	}
+	return pairs
+}
+
+// duplicateClusters собирает группы из пар; get возвращает оправдание по id
+func duplicateClusters(pairs []dedup.Pair, get func(id string) models.Excuse) []models.DuplicateCluster {
+	clusters := []models.DuplicateCluster{}
+	for _, group := range dedup.Groups(pairs) {
+		cluster := models.DuplicateCluster{Similarity: group.Similarity}
+		for _, id := range group.IDs {
+			cluster.Excuses = append(cluster.Excuses, get(id))
+		}
+		sort.SliceStable(cluster.Excuses, func(i, j int) bool {
+			return cluster.Excuses[i].CreatedAt.Before(cluster.Excuses[j].CreatedAt)
+		})
+		clusters = append(clusters, cluster)
+	}
+	return clusters
+}
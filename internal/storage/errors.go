@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Категории ошибок хранилища. Бэкенды оборачивают свои ошибки в одну из них
+// (fmt.Errorf("...: %w", ErrNotFound)), а обработчики HTTP проверяют
+// категорию через errors.Is, не зная, какой бэкенд используется.
+var (
+	// ErrNotFound - запрошенного оправдания нет
+	ErrNotFound = errors.New("not found")
+	// ErrConflict - запись противоречит существующей (например, дубликат id)
+	ErrConflict = errors.New("conflict")
+	// ErrInvalid - некорректные аргументы. Клиенту показывается только
+	// сообщение из InvalidError, текст остальных ошибок (например, драйвера)
+	// может раскрыть устройство базы.
+	ErrInvalid = errors.New("invalid argument")
+	// ErrUnavailable - бэкенд временно недоступен
+	ErrUnavailable = errors.New("storage unavailable")
+)
+
+// InvalidError - ErrInvalid с сообщением, которое можно показать клиенту
+type InvalidError struct {
+	Message string
+}
+
+// Invalidf создает InvalidError. Текст ошибок драйвера в сообщение не
+// передается.
+func Invalidf(format string, args ...interface{}) error {
+	return &InvalidError{Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *InvalidError) Error() string {
+	return ErrInvalid.Error() + ": " + e.Message
+}
+
+func (e *InvalidError) Is(target error) bool {
+	return target == ErrInvalid
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"procrastigo/internal/catalog"
+	"procrastigo/internal/config"
+	"procrastigo/internal/storage/migrations"
+	"procrastigo/pkg/logger"
+)
+
+// Поддерживаемые драйверы хранилища
+const (
+	DriverMemory   = "memory"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Когда загружать storage.seed_file при старте
+const (
+	SeedIfEmpty = "if_empty" // только в пустое хранилище
+	SeedAlways  = "always"   // при каждом старте, с обновлением по id
+	SeedNever   = "never"
+)
+
+// New создает хранилище согласно cfg.Storage.Driver и заполняет его
+// данными из cfg.Storage.SeedFile согласно cfg.Storage.SeedPolicy. Записи
+// файла проверяются по справочникам cat в режиме cfg.Storage.SeedMode.
+// Если бэкенд не удается запустить, возвращается ошибка.
+func New(ctx context.Context, cfg *config.Config, cat *catalog.Catalog) (Storage, error) {
+	var store Storage
+
+	switch cfg.StorageDriver() {
+	case DriverMemory:
+		persistence := cfg.Storage.Persistence
+		if persistence.Dir == "" {
+			store = NewMemoryStorage()
+			break
+		}
+		mem, err := OpenMemoryStorage(PersistOptions{
+			Dir:              persistence.Dir,
+			Fsync:            persistence.Fsync,
+			SnapshotInterval: persistence.SnapshotInterval,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("memory storage: %w", err)
+		}
+		store = mem
+	case DriverPostgres:
+		pg, err := NewPostgresStorage(ctx, cfg.DatabaseDSN(), cfg.Database.AutoMigrate)
+		if err != nil {
+			return nil, fmt.Errorf("postgres storage: %w", err)
+		}
+		store = pg
+	case DriverSQLite:
+		lite, err := NewSQLiteStorage(ctx, cfg.Storage.SQLite.Path, cfg.Database.AutoMigrate)
+		if err != nil {
+			return nil, fmt.Errorf("sqlite storage: %w", err)
+		}
+		store = lite
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q (supported: %s, %s, %s)",
+			cfg.StorageDriver(), DriverMemory, DriverPostgres, DriverSQLite)
+	}
+
+	opts := LoadOptions{Mode: cfg.Storage.SeedMode, Catalog: cat}
+	if err := seedFromFile(ctx, store, cfg.Storage.SeedFile, cfg.Storage.SeedPolicy, opts); err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// seedFromFile загружает оправдания из файла согласно policy: SeedIfEmpty - только
+// если в хранилище их еще нет, SeedAlways - всегда, SeedNever - никогда.
+// В режиме LoadLenient пропущенные записи выводятся в лог.
+func seedFromFile(ctx context.Context, store Storage, filename, policy string, opts LoadOptions) error {
+	if filename == "" || policy == SeedNever {
+		return nil
+	}
+
+	if policy != SeedAlways {
+		// GetStats считает только одобренные, поэтому проверяем любую запись
+		page, err := store.GetExcuses(ctx, ExcuseFilter{}, PageRequest{Limit: 1})
+		if err != nil {
+			return fmt.Errorf("failed to check storage contents: %w", err)
+		}
+		if len(page.Excuses) > 0 {
+			return nil
+		}
+	}
+
+	report, err := store.LoadFromFile(ctx, filename, opts)
+	if err != nil {
+		return fmt.Errorf("failed to seed storage: %w", err)
+	}
+	for _, re := range report.Errors {
+		logger.Warn.Printf("Seed record skipped: %s", re)
+	}
+	logger.Info.Printf("Storage seeded from %s: %d inserted, %d updated, %d unchanged, %d skipped of %d",
+		filename, report.Inserted, report.Updated, report.Unchanged, report.Skipped, report.Total)
+	return nil
+}
+
+// OpenMigrator подключается к базе выбранного драйвера для управления миграциями.
+// Вызывающий должен закрыть возвращенный *sql.DB.
+func OpenMigrator(ctx context.Context, cfg *config.Config) (*migrations.Migrator, *sql.DB, error) {
+	var db *sql.DB
+	var dialect sqlDialect
+	var err error
+
+	switch cfg.StorageDriver() {
+	case DriverPostgres:
+		db, err = openPostgres(ctx, cfg.DatabaseDSN())
+		dialect = postgresDialect{}
+	case DriverSQLite:
+		db, err = openSQLite(ctx, cfg.Storage.SQLite.Path)
+		dialect = sqliteDialect{}
+	default:
+		return nil, nil, fmt.Errorf("storage driver %q does not use migrations", cfg.StorageDriver())
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	migrator, err := migrations.New(db, dialect.name())
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return migrator, db, nil
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"fmt"
+	"procrastigo/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExcuseFilter - условия отбора оправданий. Пустые поля не ограничивают
+// выборку. Все бэкенды обязаны трактовать фильтр одинаково, как Matches.
+type ExcuseFilter struct {
+	Category      string
+	Language      string
+	Severities    []string  // любая из перечисленных
+	CreatedAfter  time.Time // created_at >= CreatedAfter
+	CreatedBefore time.Time // created_at < CreatedBefore
+	MinRating     *int      // rating >= MinRating
+	ExcludeIDs    []string  // кроме перечисленных id
+	Tags          []string  // теги в нижнем регистре, см. TagMode
+	TagMode       string    // TagModeAny (по умолчанию) или TagModeAll
+	Status        string    // статус модерации; пустой - любой
+}
+
+// Matches сообщает, проходит ли оправдание через фильтр.
+func (f ExcuseFilter) Matches(excuse models.Excuse) bool {
+	if f.Category != "" && excuse.Category != f.Category {
+		return false
+	}
+	if f.Language != "" && excuse.Language != f.Language {
+		return false
+	}
+	if len(f.Severities) > 0 && !containsString(f.Severities, excuse.Severity) {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() && excuse.CreatedAt.Before(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !excuse.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+	if f.MinRating != nil && excuse.Rating < *f.MinRating {
+		return false
+	}
+	if len(f.ExcludeIDs) > 0 && containsString(f.ExcludeIDs, excuse.ID) {
+		return false
+	}
+	if len(f.Tags) > 0 && !matchTags(excuse.Tags, f.Tags, f.TagMode) {
+		return false
+	}
+	if f.Status != "" && statusOrDefault(excuse.Status) != f.Status {
+		return false
+	}
+	return true
+}
+
+// sqlWhere строит условие WHERE (без ключевого слова) для фильтра.
+// Плейсхолдеры нумеруются с firstArg; вместе с условием возвращаются
+// аргументы запроса. Пустой фильтр дает пустую строку.
+func (f ExcuseFilter) sqlWhere(firstArg int) (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+
+	add := func(clause string, values ...interface{}) {
+		placeholders := make([]interface{}, len(values))
+		for i := range values {
+			placeholders[i] = fmt.Sprintf("$%d", firstArg+len(args)+i)
+		}
+		clauses = append(clauses, fmt.Sprintf(clause, placeholders...))
+		args = append(args, values...)
+	}
+
+	if f.Category != "" {
+		add("category = %s", f.Category)
+	}
+	if f.Language != "" {
+		add("language = %s", f.Language)
+	}
+	if len(f.Severities) > 0 {
+		add("severity IN ("+listPlaceholders(len(f.Severities))+")", stringArgs(f.Severities)...)
+	}
+	if !f.CreatedAfter.IsZero() {
+		add("created_at >= %s", f.CreatedAfter)
+	}
+	if !f.CreatedBefore.IsZero() {
+		add("created_at < %s", f.CreatedBefore)
+	}
+	if f.MinRating != nil {
+		add("rating >= %s", *f.MinRating)
+	}
+	if len(f.ExcludeIDs) > 0 {
+		add("id NOT IN ("+listPlaceholders(len(f.ExcludeIDs))+")", stringArgs(f.ExcludeIDs)...)
+	}
+	if len(f.Tags) > 0 {
+		tagged := "id IN (SELECT excuse_id FROM excuse_tags WHERE tag IN (" + listPlaceholders(len(f.Tags)) + ")"
+		if f.TagMode == TagModeAll {
+			// (excuse_id, tag) уникальна, поэтому число строк - число совпавших тегов
+			tagged += " GROUP BY excuse_id HAVING COUNT(*) = " + strconv.Itoa(countDistinct(f.Tags))
+		}
+		add(tagged+")", stringArgs(f.Tags)...)
+	}
+	if f.Status != "" {
+		add("status = %s", f.Status)
+	}
+
+	return strings.Join(clauses, " AND "), args
+}
+
+// listPlaceholders возвращает "%s, %s, ..." для n значений списка
+func listPlaceholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("%s, ", n), ", ")
+}
+
+func stringArgs(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	return args
+}
+
+func countDistinct(values []string) int {
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		seen[value] = true
+	}
+	return len(seen)
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
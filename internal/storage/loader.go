@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"procrastigo/internal/catalog"
+	"procrastigo/internal/models"
+	"strings"
+)
+
+// ExcuseFileFormat - структура для десериализации данных из JSON файла.
+// Votes заполняется в снимках MemoryStorage и при экспорте (см. transfer);
+// LoadFromFile его не читает.
+type ExcuseFileFormat struct {
+	Excuses []models.Excuse           `json:"excuses"`
+	Votes   map[string]map[string]int `json:"votes,omitempty"` // id оправдания -> голосующий -> голос
+}
+
+// Режимы загрузки файла с оправданиями
+const (
+	// LoadStrict - любая некорректная запись отменяет загрузку целиком
+	LoadStrict = "strict"
+	// LoadLenient - некорректные записи пропускаются и попадают в отчет
+	LoadLenient = "lenient"
+)
+
+// LoadOptions - параметры LoadFromFile
+type LoadOptions struct {
+	Mode    string           // LoadStrict (по умолчанию) или LoadLenient
+	Catalog *catalog.Catalog // справочники для проверки category, language, severity
+}
+
+// RecordError - причина, по которой запись файла не принята
+type RecordError struct {
+	Index  int    // позиция в массиве excuses, с нуля
+	ID     string // может быть пустым
+	Reason string
+}
+
+func (e RecordError) String() string {
+	if e.ID == "" {
+		return fmt.Sprintf("excuses[%d]: %s", e.Index, e.Reason)
+	}
+	return fmt.Sprintf("excuses[%d] (id %q): %s", e.Index, e.ID, e.Reason)
+}
+
+// LoadReport - итог загрузки файла. Записи с существующим id обновляются
+// так же, как UpdateExcuse: created_at, rating и голоса сохраняются.
+type LoadReport struct {
+	File      string
+	Total     int // записей в файле
+	Loaded    int // принято корректных записей: Inserted + Updated + Unchanged
+	Inserted  int // новые id
+	Updated   int // существующие id с измененными полями
+	Unchanged int // существующие id, совпадающие с файлом
+	Skipped   int // отброшено как некорректные
+	Errors    []RecordError
+}
+
+// LoadError возвращается, когда в режиме LoadStrict в файле есть
+// некорректные записи. Ничего не загружается; errors.Is(err, ErrInvalid).
+type LoadError struct {
+	Report *LoadReport
+}
+
+func (e *LoadError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d of %d record(s) are invalid", e.Report.File, len(e.Report.Errors), e.Report.Total)
+	for _, re := range e.Report.Errors {
+		b.WriteString("\n  - ")
+		b.WriteString(re.String())
+	}
+	return b.String()
+}
+
+func (e *LoadError) Unwrap() error {
+	return ErrInvalid
+}
+
+// ReadExcuseFile читает файл в формате ExcuseFileFormat и проверяет каждую
+// запись: непустые id и text, уникальность id, заданный created_at и
+// значения category, language, severity из opts.Catalog. Коды приводятся к
+// нижнему регистру. Возвращает корректные записи и отчет; в режиме
+// LoadStrict при наличии ошибок возвращает *LoadError и ни одной записи.
+func ReadExcuseFile(filename string, opts LoadOptions) ([]models.Excuse, *LoadReport, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	// записи разбираются по одной, чтобы ошибка типа в одной из них
+	// попала в отчет с ее индексом, а не сорвала разбор всего файла
+	var raw struct {
+		Excuses []json.RawMessage `json:"excuses"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+
+	report := &LoadReport{File: filename, Total: len(raw.Excuses)}
+	excuses := make([]models.Excuse, 0, len(raw.Excuses))
+	firstIndex := make(map[string]int, len(raw.Excuses))
+
+	for i, msg := range raw.Excuses {
+		var excuse models.Excuse
+		if err := json.Unmarshal(msg, &excuse); err != nil {
+			report.Errors = append(report.Errors, RecordError{Index: i, Reason: err.Error()})
+			continue
+		}
+
+		reasons := ValidateExcuse(&excuse, opts.Catalog)
+		if first, ok := firstIndex[excuse.ID]; ok && excuse.ID != "" {
+			reasons = append(reasons, fmt.Sprintf("duplicate id, first seen at index %d", first))
+		} else if excuse.ID != "" {
+			firstIndex[excuse.ID] = i
+		}
+
+		if len(reasons) > 0 {
+			report.Errors = append(report.Errors, RecordError{Index: i, ID: excuse.ID, Reason: strings.Join(reasons, "; ")})
+			continue
+		}
+		excuses = append(excuses, excuse)
+	}
+
+	report.Skipped = len(report.Errors)
+	if report.Skipped > 0 && opts.Mode != LoadLenient {
+		return nil, report, &LoadError{Report: report}
+	}
+	return excuses, report, nil
+}
+
+// ValidateExcuse приводит коды записи к нижнему регистру, нормализует теги
+// (см. NormalizeTags), подставляет статус approved, если он не задан, и
+// возвращает причины, по которым запись некорректна. Справочники
+// проверяются, если cat задан.
+func ValidateExcuse(excuse *models.Excuse, cat *catalog.Catalog) []string {
+	var reasons []string
+	if strings.TrimSpace(excuse.ID) == "" {
+		reasons = append(reasons, "id is empty")
+	}
+	if strings.TrimSpace(excuse.Text) == "" {
+		reasons = append(reasons, "text is empty")
+	}
+	if excuse.CreatedAt.IsZero() {
+		reasons = append(reasons, "created_at is missing")
+	}
+
+	excuse.Category = strings.ToLower(excuse.Category)
+	excuse.Language = strings.ToLower(excuse.Language)
+	excuse.Severity = strings.ToLower(excuse.Severity)
+	if tags, err := NormalizeTags(excuse.Tags); err != nil {
+		reasons = append(reasons, err.Error())
+	} else {
+		excuse.Tags = tags
+	}
+	excuse.Status = statusOrDefault(strings.ToLower(excuse.Status))
+	if !ValidStatus(excuse.Status) {
+		reasons = append(reasons, fmt.Sprintf("unknown status %q", excuse.Status))
+	}
+	if cat != nil {
+		if !cat.ValidCategory(excuse.Category) {
+			reasons = append(reasons, fmt.Sprintf("unknown category %q", excuse.Category))
+		}
+		if !cat.ValidLanguage(excuse.Language) {
+			reasons = append(reasons, fmt.Sprintf("unknown language %q", excuse.Language))
+		}
+		if !cat.ValidSeverity(excuse.Severity) {
+			reasons = append(reasons, fmt.Sprintf("unknown severity %q", excuse.Severity))
+		}
+	}
+	return reasons
+}
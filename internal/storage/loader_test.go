@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"procrastigo/internal/catalog"
+	"procrastigo/internal/models"
+	"procrastigo/pkg/logger"
+	"strings"
+	"testing"
+)
+
+func testCatalog(t *testing.T) *catalog.Catalog {
+	t.Helper()
+	cat, err := catalog.Load("../../configs/catalog.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cat
+}
+
+const mixedFile = `{"excuses": [
+  {"id": "a", "text": "ok", "category": "Tech", "language": "en", "severity": "high", "created_at": "2024-01-01T00:00:00Z"},
+  {"id": "b", "text": " ", "category": "work", "language": "ru", "severity": "low", "created_at": "2024-01-01T00:00:00Z"},
+  {"id": "a", "text": "dup", "category": "work", "language": "ru", "severity": "low", "created_at": "2024-01-01T00:00:00Z"},
+  {"id": "c", "text": "bad codes", "category": "nope", "language": "xx", "severity": "meh"},
+  {"id": "d", "text": "bad type", "rating": "high"},
+  {"id": "e", "text": "fine", "category": "general", "language": "ru", "severity": "low", "created_at": "2024-01-02T00:00:00Z"}
+]}`
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "excuses.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadExcuseFileReportsEveryInvalidRecord(t *testing.T) {
+	path := writeFile(t, mixedFile)
+
+	excuses, report, err := ReadExcuseFile(path, LoadOptions{Mode: LoadStrict, Catalog: testCatalog(t)})
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) || !errors.Is(err, ErrInvalid) {
+		t.Fatalf("strict mode: got %v, want *LoadError wrapping ErrInvalid", err)
+	}
+	if len(excuses) != 0 {
+		t.Errorf("strict mode returned %d excuses, want none", len(excuses))
+	}
+
+	wantReasons := map[int]string{
+		1: "text is empty",
+		2: "duplicate id, first seen at index 0",
+		3: `unknown category "nope"; unknown language "xx"; unknown severity "meh"`,
+		4: "cannot unmarshal",
+	}
+	if report.Total != 6 || report.Skipped != len(wantReasons) {
+		t.Fatalf("report total=%d skipped=%d, want 6 and %d", report.Total, report.Skipped, len(wantReasons))
+	}
+	for _, re := range report.Errors {
+		want, ok := wantReasons[re.Index]
+		if !ok || !strings.Contains(re.Reason, want) {
+			t.Errorf("excuses[%d]: got %q, want %q", re.Index, re.Reason, want)
+		}
+	}
+	if !strings.Contains(report.Errors[2].Reason, "created_at is missing") {
+		t.Errorf("excuses[3]: missing created_at not reported: %q", report.Errors[2].Reason)
+	}
+}
+
+func TestLoadFromFileLenientSkipsInvalidRecords(t *testing.T) {
+	path := writeFile(t, mixedFile)
+	store := NewMemoryStorage()
+	ctx := context.Background()
+
+	report, err := store.LoadFromFile(ctx, path, LoadOptions{Mode: LoadLenient, Catalog: testCatalog(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Loaded != 2 || report.Skipped != 4 {
+		t.Errorf("loaded=%d skipped=%d, want 2 and 4", report.Loaded, report.Skipped)
+	}
+
+	excuse, err := store.GetExcuse(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if excuse.Text != "ok" || excuse.Category != "tech" {
+		t.Errorf("got %+v, want the first record with lowercased category", excuse)
+	}
+}
+
+func TestShippedSeedFileIsValid(t *testing.T) {
+	_, report, err := ReadExcuseFile("../../data/excuses.json", LoadOptions{Mode: LoadStrict, Catalog: testCatalog(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total == 0 {
+		t.Error("seed file is empty")
+	}
+}
+
+func TestSeedPolicies(t *testing.T) {
+	logger.Init("production")
+	ctx := context.Background()
+	opts := LoadOptions{Mode: LoadStrict, Catalog: testCatalog(t)}
+	path := writeFile(t, `{"excuses": [
+  {"id": "a", "text": "from file", "category": "work", "language": "en", "severity": "low", "created_at": "2024-01-01T00:00:00Z"}
+]}`)
+
+	cases := []struct {
+		policy   string
+		empty    bool
+		wantText string // "" - записи нет
+	}{
+		{SeedIfEmpty, true, "from file"},
+		{SeedIfEmpty, false, "in store"},
+		{SeedAlways, false, "from file"},
+		{SeedNever, true, ""},
+	}
+	for _, tc := range cases {
+		store := NewMemoryStorage()
+		if !tc.empty {
+			// неопубликованное оправдание тоже делает хранилище непустым
+			seed(t, store, []models.Excuse{{ID: "a", Text: "in store", Category: "work", Language: "en", Severity: "low", Status: models.StatusPending}})
+		}
+		if err := seedFromFile(ctx, store, path, tc.policy, opts); err != nil {
+			t.Fatalf("%s: %v", tc.policy, err)
+		}
+
+		got, err := store.GetExcuse(ctx, "a")
+		switch {
+		case tc.wantText == "" && !errors.Is(err, ErrNotFound):
+			t.Errorf("%s: expected no excuse, got %v, %v", tc.policy, got, err)
+		case tc.wantText != "" && (err != nil || got.Text != tc.wantText):
+			t.Errorf("%s (empty=%v): got %v, %v, want text %q", tc.policy, tc.empty, got, err, tc.wantText)
+		}
+	}
+}
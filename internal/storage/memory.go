@@ -1,65 +1,106 @@
 package storage
 
 import (
-	"errors"
+	"context"
 	"fmt"
-	"io/ioutil"
+	"procrastigo/internal/dedup"
 	"procrastigo/internal/models"
+	"procrastigo/internal/search"
 	"procrastigo/pkg/utils"
+	"sort"
 	"sync"
 	"time"
-
-	"encoding/json"
 )
 
-// ExcuseFileFormat - структура для десериализации данных из JSON файла
-type ExcuseFileFormat struct {
-	Excuses []models.Excuse `json:"excuses"`
-}
-
-// MemoryStorage - простое хранилище в оперативной памяти
+// MemoryStorage - простое хранилище в оперативной памяти. Созданное через
+// OpenMemoryStorage, оно ведет журнал изменений на диске (см. persist.go).
 type MemoryStorage struct {
 	excuses map[string]models.Excuse
+	votes   map[string]map[string]int  // id оправдания -> голосующий -> голос
+	index   *search.Index              // полнотекстовый индекс по text
+	shingle map[string]dedup.Trigrams  // триграммы text для поиска дубликатов
+	tagged  map[string]map[string]bool // тег -> id оправданий с ним
 	mu      sync.RWMutex
+
+	journal   *journal // nil - без сохранения на диск
+	stop      chan struct{}
+	done      sync.WaitGroup
+	closeOnce sync.Once
+	closeErr  error
 }
 
+// NewMemoryStorage создает хранилище без сохранения на диск.
 func NewMemoryStorage() *MemoryStorage {
 	return &MemoryStorage{
 		excuses: make(map[string]models.Excuse),
+		votes:   make(map[string]map[string]int),
+		index:   search.NewIndex(),
+		shingle: make(map[string]dedup.Trigrams),
+		tagged:  make(map[string]map[string]bool),
 	}
 }
 
-// LoadFromFile загружает оправдания из JSON файла
-func (s *MemoryStorage) LoadFromFile(filename string) error {
-	data, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+// LoadFromFile загружает оправдания из JSON файла (см. ReadExcuseFile).
+func (s *MemoryStorage) LoadFromFile(ctx context.Context, filename string, opts LoadOptions) (*LoadReport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	var fileData ExcuseFileFormat
-	if err := json.Unmarshal(data, &fileData); err != nil {
-		return fmt.Errorf("json: cannot unmarshal object into Go value of type %T: %w", fileData, err)
+	excuses, report, err := ReadExcuseFile(filename, opts)
+	if err != nil {
+		return report, err
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	for _, excuse := range fileData.Excuses {
-		s.excuses[excuse.ID] = excuse
+	for _, excuse := range excuses {
+		stored, exists := s.excuses[excuse.ID]
+		if exists && stored.Text == excuse.Text && stored.Category == excuse.Category &&
+			stored.Language == excuse.Language && stored.Severity == excuse.Severity &&
+			equalTags(stored.Tags, excuse.Tags) {
+			report.Unchanged++
+			continue
+		}
+
+		if exists {
+			stored.Text = excuse.Text
+			stored.Category = excuse.Category
+			stored.Language = excuse.Language
+			stored.Severity = excuse.Severity
+			stored.Tags = excuse.Tags
+			excuse = stored
+		}
+		if err := s.record(journalEntry{Op: opPut, Excuse: &excuse}); err != nil {
+			return report, err
+		}
+		s.put(excuse)
+		if exists {
+			report.Updated++
+		} else {
+			report.Inserted++
+		}
 	}
-	return nil
+	report.Loaded = len(excuses)
+	return report, nil
 }
 
-func (s *MemoryStorage) GetRandomExcuse() (*models.Excuse, error) {
+func (s *MemoryStorage) GetRandomExcuse(ctx context.Context, filter ExcuseFilter) (*models.Excuse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	if len(s.excuses) == 0 {
-		return nil, nil
+	keys := make([]string, 0, len(s.excuses))
+	for k, excuse := range s.excuses {
+		if filter.Matches(excuse) {
+			keys = append(keys, k)
+		}
 	}
 
-	keys := make([]string, 0, len(s.excuses))
-	for k := range s.excuses {
-		keys = append(keys, k)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("random excuse: %w", ErrNotFound)
 	}
 
 	randomKey := keys[utils.RandomInt(len(keys))]
@@ -68,85 +109,359 @@ func (s *MemoryStorage) GetRandomExcuse() (*models.Excuse, error) {
 	return &excuse, nil
 }
 
-func (s *MemoryStorage) GetExcuses(category, language string, limit int) ([]models.Excuse, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+func (s *MemoryStorage) GetExcuses(ctx context.Context, filter ExcuseFilter, page PageRequest) (*models.ExcusePage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	var result []models.Excuse
-	count := 0
+	s.mu.RLock()
+	var matched []models.Excuse
 	for _, excuse := range s.excuses {
-		matches := true
-		if category != "" && excuse.Category != category {
-			matches = false
-		}
-		if language != "" && excuse.Language != language {
-			matches = false
-		}
-
-		if matches {
-			result = append(result, excuse)
-			count++
-			if limit > 0 && count >= limit {
-				break
-			}
+		if filter.Matches(excuse) {
+			matched = append(matched, excuse)
 		}
 	}
-	return result, nil
+	s.mu.RUnlock()
+
+	return paginate(matched, page)
 }
 
-func (s *MemoryStorage) CreateExcuse(excuse models.Excuse) error {
+func (s *MemoryStorage) CreateExcuse(ctx context.Context, excuse models.Excuse) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if _, exists := s.excuses[excuse.ID]; exists {
-		return errors.New("excuse already exists")
+		return fmt.Errorf("excuse %s already exists: %w", excuse.ID, ErrConflict)
 	}
+	excuse.Status = statusOrDefault(excuse.Status)
 
-	s.excuses[excuse.ID] = excuse
+	if err := s.record(journalEntry{Op: opPut, Excuse: &excuse}); err != nil {
+		return err
+	}
+	s.put(excuse)
 	return nil
 }
 
-// RateExcuse - НОВАЯ РЕАЛИЗАЦИЯ ДЛЯ УДОВЛЕТВОРЕНИЯ ИНТЕРФЕЙСУ
-func (s *MemoryStorage) RateExcuse(id string, change int) error {
+// GetExcuse возвращает оправдание по id
+func (s *MemoryStorage) GetExcuse(ctx context.Context, id string) (*models.Excuse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	excuse, exists := s.excuses[id]
+	if !exists {
+		return nil, fmt.Errorf("excuse %s: %w", id, ErrNotFound)
+	}
+	return &excuse, nil
+}
+
+// UpdateExcuse заменяет редактируемые поля и теги оправдания
+func (s *MemoryStorage) UpdateExcuse(ctx context.Context, excuse models.Excuse) (*models.Excuse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	excuse, exists := s.excuses[id]
+	stored, exists := s.excuses[excuse.ID]
 	if !exists {
-		return errors.New("excuse not found")
+		return nil, fmt.Errorf("excuse %s: %w", excuse.ID, ErrNotFound)
 	}
 
-	// Обновляем рейтинг в памяти
-	excuse.Rating += change
-	s.excuses[id] = excuse
+	stored.Text = excuse.Text
+	stored.Category = excuse.Category
+	stored.Language = excuse.Language
+	stored.Severity = excuse.Severity
+	stored.Tags = excuse.Tags
+	if err := s.record(journalEntry{Op: opPut, Excuse: &stored}); err != nil {
+		return nil, err
+	}
+	s.put(stored)
+
+	return &stored, nil
+}
+
+// DeleteExcuse удаляет оправдание вместе с голосами за него
+func (s *MemoryStorage) DeleteExcuse(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.excuses[id]; !exists {
+		return fmt.Errorf("excuse %s: %w", id, ErrNotFound)
+	}
+	if err := s.record(journalEntry{Op: opDelete, ID: id}); err != nil {
+		return err
+	}
+	s.remove(id)
 	return nil
 }
 
-func (s *MemoryStorage) GetStats() (*models.Stats, error) {
+// ModerateExcuse меняет статус оправдания на модерации
+func (s *MemoryStorage) ModerateExcuse(ctx context.Context, id, status, reason string) (*models.Excuse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkDecision(status, reason); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, exists := s.excuses[id]
+	if !exists {
+		return nil, fmt.Errorf("excuse %s: %w", id, ErrNotFound)
+	}
+	if stored.Status != models.StatusPending {
+		return nil, fmt.Errorf("excuse %s is %s, not pending: %w", id, stored.Status, ErrConflict)
+	}
+
+	stored.Status = status
+	stored.RejectionReason = reason
+	if err := s.record(journalEntry{Op: opPut, Excuse: &stored}); err != nil {
+		return nil, err
+	}
+	s.put(stored)
+
+	return &stored, nil
+}
+
+// put сохраняет оправдание и обновляет индексы. Снимки и журналы без
+// статуса модерации читаются как одобренные. Вызывается под s.mu.
+func (s *MemoryStorage) put(excuse models.Excuse) {
+	excuse.Status = statusOrDefault(excuse.Status)
+	s.untag(excuse.ID)
+	for _, tag := range excuse.Tags {
+		if s.tagged[tag] == nil {
+			s.tagged[tag] = make(map[string]bool)
+		}
+		s.tagged[tag][excuse.ID] = true
+	}
+	s.excuses[excuse.ID] = excuse
+	s.index.Add(excuse.ID, excuse.Text)
+	s.shingle[excuse.ID] = dedup.NewTrigrams(excuse.Text)
+}
+
+// remove удаляет оправдание, голоса за него и его записи в индексах.
+// Вызывается под s.mu.
+func (s *MemoryStorage) remove(id string) {
+	s.untag(id)
+	delete(s.excuses, id)
+	delete(s.votes, id)
+	s.index.Remove(id)
+	delete(s.shingle, id)
+}
+
+// untag убирает оправдание из множеств его тегов. Вызывается под s.mu.
+func (s *MemoryStorage) untag(id string) {
+	for _, tag := range s.excuses[id].Tags {
+		delete(s.tagged[tag], id)
+		if len(s.tagged[tag]) == 0 {
+			delete(s.tagged, tag)
+		}
+	}
+}
+
+// GetTags считает одобренные оправдания в множествах тегов
+func (s *MemoryStorage) GetTags(ctx context.Context) ([]models.TagCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	counts := make([]models.TagCount, 0, len(s.tagged))
+	for tag, ids := range s.tagged {
+		count := 0
+		for id := range ids {
+			if s.excuses[id].Status == models.StatusApproved {
+				count++
+			}
+		}
+		if count > 0 {
+			counts = append(counts, models.TagCount{Tag: tag, Count: count})
+		}
+	}
+	s.mu.RUnlock()
+
+	sortTagCounts(counts)
+	return counts, nil
+}
+
+// SearchExcuses ищет по инвертированному индексу, который обновляется
+// при каждом изменении текста
+func (s *MemoryStorage) SearchExcuses(ctx context.Context, query SearchQuery) ([]models.SearchHit, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	terms, err := query.terms()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matches := s.index.Search(terms, func(id string) bool {
+		return query.Filter.Matches(s.excuses[id])
+	})
+	return searchHits(matches, terms, query.limit(), func(id string) models.Excuse {
+		return s.excuses[id]
+	}), nil
+}
+
+// FindSimilar сравнивает text с опубликованными оправданиями по заранее
+// посчитанным триграммам
+func (s *MemoryStorage) FindSimilar(ctx context.Context, text string, threshold float64, limit int) ([]models.SimilarExcuse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkThreshold(threshold); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return similarExcuses(text, threshold, limit, s.sorted(), s.trigrams), nil
+}
+
+// DuplicateClusters сравнивает все оправдания попарно
+func (s *MemoryStorage) DuplicateClusters(ctx context.Context, threshold float64) ([]models.DuplicateCluster, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkThreshold(threshold); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	pairs := similarPairs(s.sorted(), threshold, s.trigrams)
+	return duplicateClusters(pairs, func(id string) models.Excuse { return s.excuses[id] }), nil
+}
+
+// sorted возвращает все оправдания по id. Вызывается под s.mu.
+func (s *MemoryStorage) sorted() []models.Excuse {
+	excuses := make([]models.Excuse, 0, len(s.excuses))
+	for _, excuse := range s.excuses {
+		excuses = append(excuses, excuse)
+	}
+	sort.Slice(excuses, func(i, j int) bool { return excuses[i].ID < excuses[j].ID })
+	return excuses
+}
+
+// trigrams возвращает триграммы оправдания. Вызывается под s.mu.
+func (s *MemoryStorage) trigrams(excuse models.Excuse) dedup.Trigrams {
+	return s.shingle[excuse.ID]
+}
+
+// RateExcuse сохраняет голос и пересчитывает рейтинг из всех голосов
+func (s *MemoryStorage) RateExcuse(ctx context.Context, id, voterID string, vote int) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if vote < -1 || vote > 1 {
+		return 0, Invalidf("vote must be -1, 0 or 1, got %d", vote)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.excuses[id]; !exists {
+		return 0, fmt.Errorf("excuse %s: %w", id, ErrNotFound)
+	}
+	if err := s.record(journalEntry{Op: opVote, ID: id, Voter: voterID, Vote: vote}); err != nil {
+		return 0, err
+	}
+	return s.applyVote(id, voterID, vote), nil
+}
+
+func (s *MemoryStorage) GetVotes(ctx context.Context, id string) (map[string]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	stats := &models.Stats{
-		TotalExcuses: len(s.excuses),
-		ExcusesToday: 0,
+	if _, exists := s.excuses[id]; !exists {
+		return nil, fmt.Errorf("excuse %s: %w", id, ErrNotFound)
+	}
+	votes := make(map[string]int, len(s.votes[id]))
+	for voter, vote := range s.votes[id] {
+		votes[voter] = vote
+	}
+	return votes, nil
+}
+
+// applyVote записывает голос и возвращает пересчитанный рейтинг.
+// Вызывается под s.mu.
+func (s *MemoryStorage) applyVote(id, voterID string, vote int) int {
+	voters := s.votes[id]
+	if voters == nil {
+		voters = make(map[string]int)
+		s.votes[id] = voters
 	}
+	if vote == 0 {
+		delete(voters, voterID)
+	} else {
+		voters[voterID] = vote
+	}
+
+	rating := 0
+	for _, v := range voters {
+		rating += v
+	}
+	if excuse, exists := s.excuses[id]; exists {
+		excuse.Rating = rating
+		s.excuses[id] = excuse
+	}
+	return rating
+}
+
+func (s *MemoryStorage) GetStats(ctx context.Context) (*models.Stats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	now := time.Now().Truncate(24 * time.Hour)
+	stats := &models.Stats{}
+
+	today := utils.GetStartOfDay()
+	tomorrow := today.Add(24 * time.Hour)
 	categoryCounts := make(map[string]int)
-	maxCount := 0
 
 	for _, excuse := range s.excuses {
-		// Статистика за сегодня
-		if excuse.CreatedAt.Truncate(24 * time.Hour).Equal(now) {
+		if excuse.Status != models.StatusApproved {
+			continue
+		}
+		stats.TotalExcuses++
+		// Статистика за сегодня (сутки по UTC)
+		if !excuse.CreatedAt.Before(today) && excuse.CreatedAt.Before(tomorrow) {
 			stats.ExcusesToday++
 		}
-
-		// Самая популярная категория
 		categoryCounts[excuse.Category]++
-		if categoryCounts[excuse.Category] > maxCount {
-			maxCount = categoryCounts[excuse.Category]
-			stats.MostPopularCategory = excuse.Category
+	}
+
+	// Самая популярная категория; при равенстве - первая по алфавиту
+	maxCount := 0
+	for category, count := range categoryCounts {
+		if count > maxCount || count == maxCount && category < stats.MostPopularCategory {
+			maxCount = count
+			stats.MostPopularCategory = category
 		}
 	}
 
@@ -155,5 +470,13 @@ func (s *MemoryStorage) GetStats() (*models.Stats, error) {
 	return stats, nil
 }
 
+// Close делает последний снимок и закрывает журнал, если хранилище
+// сохраняется на диск; иначе ничего не делает. Повторный вызов
+// возвращает результат первого.
+func (s *MemoryStorage) Close() error {
+	s.closeOnce.Do(func() { s.closeErr = s.closeJournal() })
+	return s.closeErr
+}
+
 // Утверждение, что *MemoryStorage реализует Storage
 var _ Storage = (*MemoryStorage)(nil)
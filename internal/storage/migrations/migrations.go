@@ -0,0 +1,381 @@
+// Package migrations применяет версионированные SQL миграции к базе данных.
+//
+// Файлы миграций встроены в бинарник и называются
+// NNNN_описание.up.sql / NNNN_описание.down.sql. Примененные версии
+// хранятся в таблице schema_migrations вместе с контрольной суммой up файла,
+// поэтому изменение уже примененной миграции обнаруживается при запуске.
+//
+// Миграции общие для всех SQL бэкендов. Если SQL версии не подходит
+// диалекту, в каталоге sql/<диалект> лежит ее замена с тем же номером.
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed sql/*.sql sql/sqlite/*.sql
+var embedded embed.FS
+
+// Поддерживаемые диалекты
+const (
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite"
+)
+
+var fileNameRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration - одна версия схемы
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Status описывает состояние одной миграции в базе
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator применяет и откатывает миграции
+type Migrator struct {
+	db         *sql.DB
+	dialect    string
+	migrations []Migration
+}
+
+// New создает Migrator со встроенными миграциями для dialect.
+func New(db *sql.DB, dialect string) (*Migrator, error) {
+	if dialect != DialectPostgres && dialect != DialectSQLite {
+		return nil, fmt.Errorf("unknown migration dialect %q", dialect)
+	}
+
+	sub, err := fs.Sub(embedded, "sql")
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := Load(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	if overrides, err := fs.Sub(embedded, path.Join("sql", dialect)); err == nil {
+		if _, statErr := fs.Stat(overrides, "."); statErr == nil {
+			replacements, err := Load(overrides)
+			if err != nil {
+				return nil, fmt.Errorf("%s overrides: %w", dialect, err)
+			}
+			if migrations, err = override(migrations, replacements); err != nil {
+				return nil, fmt.Errorf("%s overrides: %w", dialect, err)
+			}
+		}
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// override заменяет миграции версиями из replacements. Замена должна
+// соответствовать существующей версии с тем же именем.
+func override(migrations, replacements []Migration) ([]Migration, error) {
+	byVersion := make(map[int]int, len(migrations))
+	for i, mig := range migrations {
+		byVersion[mig.Version] = i
+	}
+	for _, rep := range replacements {
+		i, ok := byVersion[rep.Version]
+		if !ok || migrations[i].Name != rep.Name {
+			return nil, fmt.Errorf("override %04d_%s has no matching migration", rep.Version, rep.Name)
+		}
+		if rep.Down == "" {
+			rep.Down = migrations[i].Down
+		}
+		migrations[i] = rep
+	}
+	return migrations, nil
+}
+
+// Load читает миграции из корня fsys и сортирует их по версии.
+// У каждой версии должен быть up файл; down файл необязателен.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		m := fileNameRe.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(m[1])
+		data, err := fs.ReadFile(fsys, path.Join(".", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %04d has conflicting names %q and %q", version, mig.Name, m[2])
+		}
+
+		if m[3] == "up" {
+			mig.Up = string(data)
+			sum := sha256.Sum256(data)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(data)
+		}
+	}
+
+	result := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up file", mig.Version, mig.Name)
+		}
+		result = append(result, *mig)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+
+	return result, nil
+}
+
+// ensureTable создает таблицу учета миграций. Драйвер SQLite читает
+// время только из колонок с типом ровно TIMESTAMP.
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	timestamp := "TIMESTAMP WITH TIME ZONE"
+	if m.dialect == DialectSQLite {
+		timestamp = "TIMESTAMP"
+	}
+	_, err := m.db.ExecContext(ctx, `
+    CREATE TABLE IF NOT EXISTS schema_migrations (
+        version INTEGER PRIMARY KEY,
+        name TEXT NOT NULL,
+        checksum TEXT NOT NULL,
+        applied_at `+timestamp+` NOT NULL
+    )`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+type appliedRecord struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+// applied возвращает примененные версии
+func (m *Migrator) applied(ctx context.Context) (map[int]appliedRecord, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, "SELECT version, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[int]appliedRecord)
+	for rows.Next() {
+		var version int
+		var rec appliedRecord
+		if err := rows.Scan(&version, &rec.checksum, &rec.appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations row: %w", err)
+		}
+		result[version] = rec
+	}
+	return result, rows.Err()
+}
+
+// verify проверяет, что примененные миграции не изменились и известны бинарнику.
+func (m *Migrator) verify(applied map[int]appliedRecord) error {
+	known := make(map[int]bool, len(m.migrations))
+	for _, mig := range m.migrations {
+		known[mig.Version] = true
+		rec, ok := applied[mig.Version]
+		if ok && rec.checksum != mig.Checksum {
+			return fmt.Errorf("migration %04d_%s was modified after it was applied (checksum mismatch)", mig.Version, mig.Name)
+		}
+	}
+	for version := range applied {
+		if !known[version] {
+			return fmt.Errorf("database has migration %04d applied which is unknown to this binary", version)
+		}
+	}
+	return nil
+}
+
+// Status возвращает состояние всех известных миграций.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		rec, ok := applied[mig.Version]
+		result = append(result, Status{Migration: mig, Applied: ok, AppliedAt: rec.appliedAt})
+	}
+	return result, m.verify(applied)
+}
+
+// Pending возвращает количество еще не примененных миграций.
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, st := range statuses {
+		if !st.Applied {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// Up применяет все ожидающие миграции по порядку и возвращает их количество.
+// Каждая миграция выполняется в отдельной транзакции.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := m.verify(applied); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		err := m.inTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx,
+				"INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)",
+				mig.Version, mig.Name, mig.Checksum, time.Now().UTC())
+			return err
+		})
+		if err != nil {
+			return count, fmt.Errorf("failed to apply migration %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Down откатывает steps последних примененных миграций и возвращает их количество.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := m.verify(applied); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(m.migrations) - 1; i >= 0 && count < steps; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if mig.Down == "" {
+			return count, fmt.Errorf("migration %04d_%s has no down file", mig.Version, mig.Name)
+		}
+		err := m.inTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", mig.Version)
+			return err
+		})
+		if err != nil {
+			return count, fmt.Errorf("failed to revert migration %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// lockKey - ключ pg_advisory_lock, общий для всех экземпляров сервиса
+const lockKey int64 = 0x70726f63 // "proc"
+
+// lock не дает нескольким экземплярам сервиса применять или откатывать
+// миграции одновременно: второй ждет первого и затем видит уже
+// примененные версии. В PostgreSQL это сессионная advisory блокировка на
+// отдельном соединении. Файл SQLite рассчитан на один экземпляр сервиса,
+// и блокировка там не берется.
+func (m *Migrator) lock(ctx context.Context) (unlock func(), err error) {
+	if m.dialect != DialectPostgres {
+		return func() {}, nil
+	}
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	return func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey); err != nil {
+			// соединение с блокировкой нельзя возвращать в пул: закрываем
+			// его, и сервер снимает блокировку сам
+			conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}, nil
+}
+
+func (m *Migrator) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,198 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	_ "modernc.org/sqlite"
+)
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "test.db")+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// testFS - две миграции, у второй нет down файла
+var testFS = fstest.MapFS{
+	"0001_create_items.up.sql":   {Data: []byte("CREATE TABLE items (id INTEGER PRIMARY KEY);")},
+	"0001_create_items.down.sql": {Data: []byte("DROP TABLE items;")},
+	"0002_items_name.up.sql":     {Data: []byte("ALTER TABLE items ADD COLUMN name TEXT;")},
+}
+
+func newTestMigrator(t *testing.T, fsys fstest.MapFS) *Migrator {
+	t.Helper()
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Migrator{db: openDB(t), dialect: DialectSQLite, migrations: migrations}
+}
+
+func applied(t *testing.T, m *Migrator) []bool {
+	t.Helper()
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make([]bool, len(statuses))
+	for i, st := range statuses {
+		result[i] = st.Applied
+		if st.Applied && st.AppliedAt.IsZero() {
+			t.Errorf("migration %04d: applied without applied_at", st.Version)
+		}
+	}
+	return result
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(testFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[0].Version != 1 || migrations[1].Name != "items_name" {
+		t.Fatalf("unexpected migrations: %+v", migrations)
+	}
+	if migrations[0].Checksum == "" || migrations[0].Checksum == migrations[1].Checksum || migrations[1].Down != "" {
+		t.Errorf("unexpected migrations: %+v", migrations)
+	}
+
+	for name, fsys := range map[string]fstest.MapFS{
+		"bad name":          {"0001_Items.up.sql": {}},
+		"no up file":        {"0001_items.down.sql": {Data: []byte("DROP TABLE items;")}},
+		"conflicting names": {"0001_items.up.sql": {Data: []byte("SELECT 1;")}, "0001_things.down.sql": {}},
+	} {
+		if _, err := Load(fsys); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestOverride(t *testing.T) {
+	migrations, _ := Load(testFS)
+	replaced, err := override(migrations, []Migration{{Version: 2, Name: "items_name", Up: "SELECT 2;", Checksum: "x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replaced[1].Up != "SELECT 2;" || replaced[0].Up == "" {
+		t.Errorf("unexpected override result: %+v", replaced)
+	}
+	if _, err := override(migrations, []Migration{{Version: 2, Name: "other"}}); err == nil {
+		t.Error("override with another name accepted")
+	}
+	if _, err := override(migrations, []Migration{{Version: 3, Name: "items_name"}}); err == nil {
+		t.Error("override of unknown version accepted")
+	}
+}
+
+func TestUpDownStatus(t *testing.T) {
+	ctx := context.Background()
+	m := newTestMigrator(t, testFS)
+
+	if got := applied(t, m); got[0] || got[1] {
+		t.Fatalf("fresh database: applied %v", got)
+	}
+	if n, err := m.Up(ctx); err != nil || n != 2 {
+		t.Fatalf("Up: %d, %v", n, err)
+	}
+	if got := applied(t, m); !got[0] || !got[1] {
+		t.Fatalf("after Up: applied %v", got)
+	}
+	if n, err := m.Up(ctx); err != nil || n != 0 {
+		t.Fatalf("second Up: %d, %v", n, err)
+	}
+	if _, err := m.db.ExecContext(ctx, "INSERT INTO items (id, name) VALUES (1, 'a')"); err != nil {
+		t.Fatalf("schema not applied: %v", err)
+	}
+
+	// у 0002 нет down файла: откат останавливается на ней
+	if n, err := m.Down(ctx, 1); err == nil || n != 0 || !strings.Contains(err.Error(), "no down file") {
+		t.Fatalf("Down without down file: %d, %v", n, err)
+	}
+
+	m.migrations[1].Down = "ALTER TABLE items DROP COLUMN name;"
+	if n, err := m.Down(ctx, 1); err != nil || n != 1 {
+		t.Fatalf("Down 1: %d, %v", n, err)
+	}
+	if got := applied(t, m); !got[0] || got[1] {
+		t.Fatalf("after Down 1: applied %v", got)
+	}
+	if pending, err := m.Pending(ctx); err != nil || pending != 1 {
+		t.Fatalf("Pending: %d, %v", pending, err)
+	}
+	if n, err := m.Down(ctx, 5); err != nil || n != 1 {
+		t.Fatalf("Down all: %d, %v", n, err)
+	}
+	if _, err := m.db.ExecContext(ctx, "SELECT 1 FROM items"); err == nil {
+		t.Fatal("table left after Down")
+	}
+}
+
+func TestUpRollsBackFailedMigration(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"0001_create_items.up.sql": testFS["0001_create_items.up.sql"],
+		"0002_broken.up.sql":       {Data: []byte("CREATE TABLE things (id INTEGER); SELECT * FROM nope;")},
+	}
+	m := newTestMigrator(t, fsys)
+
+	if n, err := m.Up(ctx); err == nil || n != 1 || !strings.Contains(err.Error(), "0002_broken") {
+		t.Fatalf("Up: %d, %v", n, err)
+	}
+	if got := applied(t, m); !got[0] || got[1] {
+		t.Fatalf("applied %v", got)
+	}
+	// частично выполненная миграция откатывается целиком
+	if _, err := m.db.ExecContext(ctx, "SELECT 1 FROM things"); err == nil {
+		t.Fatal("table from failed migration left behind")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	m := newTestMigrator(t, testFS)
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.db.ExecContext(ctx, "UPDATE schema_migrations SET checksum = 'changed' WHERE version = 1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("modified migration: got %v", err)
+	}
+	if _, err := m.Status(ctx); err == nil {
+		t.Error("Status must report a modified migration")
+	}
+
+	m.migrations = m.migrations[1:]
+	if _, err := m.Down(ctx, 1); err == nil || !strings.Contains(err.Error(), "unknown to this binary") {
+		t.Errorf("unknown applied migration: got %v", err)
+	}
+}
+
+func TestEmbeddedSQLite(t *testing.T) {
+	ctx := context.Background()
+	m, err := New(openDB(t), DialectSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := m.Up(ctx)
+	if err != nil || n != len(m.migrations) {
+		t.Fatalf("Up: %d, %v; want %d", n, err, len(m.migrations))
+	}
+	if n, err := m.Down(ctx, n); err != nil || n != len(m.migrations) {
+		t.Fatalf("Down: %d, %v", n, err)
+	}
+	if _, err := New(openDB(t), "mysql"); err == nil {
+		t.Error("unknown dialect accepted")
+	}
+}
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS excuses (
+    id VARCHAR(50) PRIMARY KEY,
+    text TEXT NOT NULL,
+    category VARCHAR(50) NOT NULL,
+    language VARCHAR(10) NOT NULL,
+    severity VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    rating INTEGER DEFAULT 0
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_excuses_created_at;
+DROP INDEX IF EXISTS idx_excuses_rating;
+DROP INDEX IF EXISTS idx_excuses_category_language;
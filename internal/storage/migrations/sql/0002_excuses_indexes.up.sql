@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS idx_excuses_category_language ON excuses (category, language);
+CREATE INDEX IF NOT EXISTS idx_excuses_rating ON excuses (rating DESC, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_excuses_created_at ON excuses (created_at);
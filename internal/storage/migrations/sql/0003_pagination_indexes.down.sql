@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_excuses_created_at_page;
+DROP INDEX IF EXISTS idx_excuses_rating_page;
+CREATE INDEX IF NOT EXISTS idx_excuses_rating ON excuses (rating DESC, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_excuses_created_at ON excuses (created_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_excuses_rating;
+DROP INDEX IF EXISTS idx_excuses_created_at;
+CREATE INDEX IF NOT EXISTS idx_excuses_rating_page ON excuses (rating DESC, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_excuses_created_at_page ON excuses (created_at, id);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS votes (
+    excuse_id VARCHAR(50) NOT NULL REFERENCES excuses (id) ON DELETE CASCADE,
+    voter_id VARCHAR(100) NOT NULL,
+    value SMALLINT NOT NULL CHECK (value IN (-1, 1)),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (excuse_id, voter_id)
+);
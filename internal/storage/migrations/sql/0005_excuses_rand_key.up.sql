@@ -0,0 +1,2 @@
+ALTER TABLE excuses ADD COLUMN IF NOT EXISTS rand_key DOUBLE PRECISION NOT NULL DEFAULT random();
+CREATE INDEX IF NOT EXISTS idx_excuses_rand_key ON excuses (rand_key);
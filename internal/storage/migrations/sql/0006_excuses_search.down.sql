@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_excuses_search;
+ALTER TABLE excuses DROP COLUMN IF EXISTS search_vector;
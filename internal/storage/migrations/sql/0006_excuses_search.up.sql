@@ -0,0 +1,4 @@
+-- Конфигурация russian стеммит кириллицу русским snowball, латиницу - английским
+ALTER TABLE excuses ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('russian', text)) STORED;
+CREATE INDEX IF NOT EXISTS idx_excuses_search ON excuses USING GIN (search_vector);
@@ -0,0 +1,2 @@
+-- расширение pg_trgm не удаляется: им могут пользоваться другие объекты базы
+DROP INDEX IF EXISTS idx_excuses_text_trgm;
@@ -0,0 +1,3 @@
+-- Триграммный индекс для поиска почти одинаковых оправданий (оператор %)
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_excuses_text_trgm ON excuses USING GIN (text gin_trgm_ops);
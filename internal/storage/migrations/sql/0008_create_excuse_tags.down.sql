@@ -0,0 +1 @@
+DROP TABLE IF EXISTS excuse_tags;
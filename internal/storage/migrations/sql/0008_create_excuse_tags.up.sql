@@ -0,0 +1,7 @@
+-- Теги оправданий; индекс по tag нужен фильтру tags и подсчету /tags
+CREATE TABLE IF NOT EXISTS excuse_tags (
+    excuse_id VARCHAR(50) NOT NULL REFERENCES excuses (id) ON DELETE CASCADE,
+    tag VARCHAR(32) NOT NULL,
+    PRIMARY KEY (excuse_id, tag)
+);
+CREATE INDEX IF NOT EXISTS idx_excuse_tags_tag ON excuse_tags (tag);
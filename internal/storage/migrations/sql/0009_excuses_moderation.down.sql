@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_excuses_status_created_at;
+ALTER TABLE excuses DROP COLUMN IF EXISTS rejection_reason;
+ALTER TABLE excuses DROP COLUMN IF EXISTS status;
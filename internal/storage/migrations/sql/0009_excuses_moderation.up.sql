@@ -0,0 +1,5 @@
+-- Статус модерации; уже существующие оправдания считаются одобренными
+ALTER TABLE excuses ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'approved';
+ALTER TABLE excuses ADD COLUMN IF NOT EXISTS rejection_reason TEXT;
+-- очередь модерации и публичные выборки отбирают строки по статусу
+CREATE INDEX IF NOT EXISTS idx_excuses_status_created_at ON excuses (status, created_at);
@@ -0,0 +1,6 @@
+-- Индексы страниц сравнивают id побайтно (COLLATE "C"), как запросы и
+-- MemoryStorage: collation базы по умолчанию игнорирует пунктуацию
+DROP INDEX IF EXISTS idx_excuses_rating_page;
+DROP INDEX IF EXISTS idx_excuses_created_at_page;
+CREATE INDEX IF NOT EXISTS idx_excuses_rating_page ON excuses (rating DESC, created_at DESC, id COLLATE "C" DESC);
+CREATE INDEX IF NOT EXISTS idx_excuses_created_at_page ON excuses (created_at, id COLLATE "C");
@@ -0,0 +1,8 @@
+-- SQLite: драйвер возвращает time.Time только для колонок типа TIMESTAMP
+CREATE TABLE IF NOT EXISTS votes (
+    excuse_id VARCHAR(50) NOT NULL REFERENCES excuses (id) ON DELETE CASCADE,
+    voter_id VARCHAR(100) NOT NULL,
+    value SMALLINT NOT NULL CHECK (value IN (-1, 1)),
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (excuse_id, voter_id)
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_excuses_rand_key;
+ALTER TABLE excuses DROP COLUMN rand_key;
@@ -0,0 +1,5 @@
+-- SQLite: нет ADD COLUMN IF NOT EXISTS и вычисляемого DEFAULT при ALTER TABLE;
+-- новые строки получают rand_key из приложения
+ALTER TABLE excuses ADD COLUMN rand_key DOUBLE PRECISION NOT NULL DEFAULT 0;
+UPDATE excuses SET rand_key = abs(random() % 1000000000) / 1000000000.0;
+CREATE INDEX IF NOT EXISTS idx_excuses_rand_key ON excuses (rand_key);
@@ -0,0 +1,2 @@
+-- SQLite: полнотекстового индекса нет, поиск ранжирует строки в приложении
+-- тем же анализатором, что и memory хранилище
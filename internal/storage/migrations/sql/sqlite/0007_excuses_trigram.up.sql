@@ -0,0 +1 @@
+-- SQLite: триграммного индекса нет, сходство считается в приложении
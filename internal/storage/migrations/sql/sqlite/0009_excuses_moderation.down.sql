@@ -0,0 +1,4 @@
+-- SQLite: нет DROP COLUMN IF EXISTS
+DROP INDEX IF EXISTS idx_excuses_status_created_at;
+ALTER TABLE excuses DROP COLUMN rejection_reason;
+ALTER TABLE excuses DROP COLUMN status;
@@ -0,0 +1,7 @@
+-- SQLite: нет ADD COLUMN IF NOT EXISTS; миграция выполняется в транзакции
+-- и при ошибке откатывается целиком
+-- Статус модерации; уже существующие оправдания считаются одобренными
+ALTER TABLE excuses ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'approved';
+ALTER TABLE excuses ADD COLUMN rejection_reason TEXT;
+-- очередь модерации и публичные выборки отбирают строки по статусу
+CREATE INDEX IF NOT EXISTS idx_excuses_status_created_at ON excuses (status, created_at);
@@ -0,0 +1 @@
+-- SQLite: откатывать нечего
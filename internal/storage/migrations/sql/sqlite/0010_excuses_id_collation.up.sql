@@ -0,0 +1 @@
+-- SQLite: collation по умолчанию (BINARY) уже сравнивает id побайтно
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"procrastigo/internal/models"
+	"strings"
+)
+
+// ValidStatus сообщает, известен ли статус модерации.
+func ValidStatus(status string) bool {
+	switch status {
+	case models.StatusApproved, models.StatusPending, models.StatusRejected:
+		return true
+	}
+	return false
+}
+
+// statusOrDefault - статус для сохранения: пустой означает одобренное
+func statusOrDefault(status string) string {
+	if status == "" {
+		return models.StatusApproved
+	}
+	return status
+}
+
+// checkDecision проверяет решение модератора: approved без причины или
+// rejected с непустой причиной
+func checkDecision(status, reason string) error {
+	switch {
+	case status == models.StatusApproved && reason != "":
+		return Invalidf("approved excuse cannot have a rejection reason")
+	case status == models.StatusRejected && strings.TrimSpace(reason) == "":
+		return Invalidf("rejection reason is required")
+	case status != models.StatusApproved && status != models.StatusRejected:
+		return Invalidf("moderation decision must be %s or %s, got %q", models.StatusApproved, models.StatusRejected, status)
+	}
+	return nil
+}
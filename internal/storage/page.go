@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"procrastigo/internal/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Порядки сортировки списка оправданий. Во всех порядках последним ключом
+// идет id, поэтому порядок строгий и одинаковый во всех бэкендах.
+const (
+	SortRating        = "rating"      // rating, created_at, id по убыванию
+	SortCreatedAt     = "created_at"  // created_at, id по возрастанию
+	SortCreatedAtDesc = "-created_at" // created_at, id по убыванию
+)
+
+// ErrInvalidCursor возвращается для поврежденного курсора или курсора,
+// выданного для другого порядка сортировки. Относится к ErrInvalid.
+var ErrInvalidCursor = Invalidf("invalid cursor")
+
+// PageRequest - параметры страницы списка. Limit <= 0 означает без ограничения.
+type PageRequest struct {
+	Sort   string
+	Limit  int
+	Cursor string
+}
+
+// ValidSort сообщает, поддерживается ли порядок сортировки.
+func ValidSort(s string) bool {
+	switch s {
+	case SortRating, SortCreatedAt, SortCreatedAtDesc:
+		return true
+	}
+	return false
+}
+
+func (p PageRequest) sortOrDefault() string {
+	if p.Sort == "" {
+		return SortRating
+	}
+	return p.Sort
+}
+
+// cursor - позиция последнего отданного элемента. Клиенту он передается
+// как непрозрачная base64 строка.
+type cursor struct {
+	Sort      string    `json:"s"`
+	Rating    int       `json:"r"`
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+func newCursor(sortBy string, last models.Excuse) cursor {
+	return cursor{Sort: sortBy, Rating: last.Rating, CreatedAt: last.CreatedAt, ID: last.ID}
+}
+
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor разбирает курсор и проверяет, что он выдан для sortBy.
+func decodeCursor(token, sortBy string) (*cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != sortBy {
+		return nil, fmt.Errorf("%w: issued for sort=%s", ErrInvalidCursor, c.Sort)
+	}
+	return &c, nil
+}
+
+// before сообщает, идет ли a раньше b в порядке sortBy.
+func before(sortBy string, a, b cursor) bool {
+	switch sortBy {
+	case SortCreatedAt:
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	case SortCreatedAtDesc:
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	default:
+		if a.Rating != b.Rating {
+			return a.Rating > b.Rating
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	}
+}
+
+// paginate сортирует уже отфильтрованные оправдания и вырезает страницу
+// после курсора. Используется бэкендами, которые не умеют делать это сами.
+func paginate(excuses []models.Excuse, page PageRequest) (*models.ExcusePage, error) {
+	sortBy := page.sortOrDefault()
+	if !ValidSort(sortBy) {
+		return nil, Invalidf("unknown sort %q", sortBy)
+	}
+	after, err := decodeCursor(page.Cursor, sortBy)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(excuses, func(i, j int) bool {
+		return before(sortBy, newCursor(sortBy, excuses[i]), newCursor(sortBy, excuses[j]))
+	})
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(excuses), func(i int) bool {
+			return before(sortBy, *after, newCursor(sortBy, excuses[i]))
+		})
+	}
+	excuses = excuses[start:]
+
+	return buildPage(excuses, sortBy, page.Limit), nil
+}
+
+// buildPage обрезает выборку до limit и выдает курсор, если есть еще элементы.
+// excuses должны содержать хотя бы limit+1 элемент, чтобы следующая страница
+// была обнаружена.
+func buildPage(excuses []models.Excuse, sortBy string, limit int) *models.ExcusePage {
+	result := &models.ExcusePage{Excuses: []models.Excuse{}}
+	if limit > 0 && len(excuses) > limit {
+		excuses = excuses[:limit]
+		result.NextCursor = newCursor(sortBy, excuses[len(excuses)-1]).encode()
+	}
+	result.Excuses = append(result.Excuses, excuses...)
+	return result
+}
+
+// sqlOrder возвращает ORDER BY и условие продолжения после курсора для SQL
+// бэкендов. Условие использует сравнение кортежей, поэтому все ключи в
+// одном порядке сортировки идут в одном направлении. id сравниваются
+// побайтно (dialect.byteOrder), чтобы порядок совпадал с MemoryStorage.
+func sqlOrder(dialect sqlDialect, sortBy string, after *cursor, firstArg int) (orderBy, where string, args []interface{}) {
+	var columns []string
+	var values []interface{}
+	direction, op := "DESC", "<"
+
+	id := dialect.byteOrder("id")
+	switch sortBy {
+	case SortCreatedAt:
+		columns = []string{"created_at", id}
+		direction, op = "ASC", ">"
+	case SortCreatedAtDesc:
+		columns = []string{"created_at", id}
+	default:
+		columns = []string{"rating", "created_at", id}
+	}
+
+	parts := make([]string, len(columns))
+	for i, column := range columns {
+		parts[i] = column + " " + direction
+	}
+	orderBy = strings.Join(parts, ", ")
+
+	if after == nil {
+		return orderBy, "", nil
+	}
+
+	for _, column := range columns {
+		switch column {
+		case "rating":
+			values = append(values, after.Rating)
+		case "created_at":
+			values = append(values, after.CreatedAt)
+		case id:
+			values = append(values, after.ID)
+		}
+	}
+	placeholders := make([]string, len(values))
+	for i := range values {
+		placeholders[i] = fmt.Sprintf("$%d", firstArg+i)
+	}
+	where = fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), op, strings.Join(placeholders, ", "))
+	return orderBy, where, values
+}
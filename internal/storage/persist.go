@@ -0,0 +1,367 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"procrastigo/internal/models"
+	"procrastigo/pkg/logger"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Политики fsync журнала MemoryStorage
+const (
+	FsyncAlways   = "always"   // после каждой записи: изменения не теряются
+	FsyncInterval = "interval" // раз в fsyncInterval: можно потерять последнюю секунду
+	FsyncNever    = "never"    // когда решит ОС
+)
+
+const (
+	snapshotFile  = "snapshot.json"
+	journalFile   = "journal.ndjson"
+	fsyncInterval = time.Second
+)
+
+// PersistOptions - параметры сохранения MemoryStorage на диск
+type PersistOptions struct {
+	Dir              string        // каталог для снимка и журнала
+	Fsync            string        // FsyncAlways, FsyncInterval или FsyncNever
+	SnapshotInterval time.Duration // период снимков; 0 - только при Close
+}
+
+// Операции журнала
+const (
+	opPut    = "put"
+	opDelete = "delete"
+	opVote   = "vote"
+)
+
+// journalEntry - одна строка журнала. Операции идемпотентны (put хранит
+// запись целиком, vote - голос, а не приращение), поэтому повторное
+// применение журнала поверх снимка, который уже их содержит, безопасно.
+type journalEntry struct {
+	Op     string         `json:"op"`
+	Excuse *models.Excuse `json:"excuse,omitempty"`
+	ID     string         `json:"id,omitempty"`
+	Voter  string         `json:"voter,omitempty"`
+	Vote   int            `json:"vote,omitempty"`
+}
+
+// journal - журнал упреждающей записи: изменение сначала дописывается
+// в файл и только потом применяется в памяти.
+type journal struct {
+	opts  PersistOptions
+	mu    sync.Mutex // file и dirty; append вызывается под MemoryStorage.mu, sync - из фоновой горутины
+	file  *os.File
+	dirty bool
+}
+
+// OpenMemoryStorage создает MemoryStorage, сохраняющее изменения в opts.Dir.
+// Состояние восстанавливается из последнего снимка и журнала после него.
+// В фоне делаются снимки раз в opts.SnapshotInterval; Close делает
+// последний снимок.
+func OpenMemoryStorage(opts PersistOptions) (*MemoryStorage, error) {
+	switch opts.Fsync {
+	case FsyncAlways, FsyncInterval, FsyncNever:
+	default:
+		return nil, fmt.Errorf("unknown fsync policy %q", opts.Fsync)
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	s := NewMemoryStorage()
+	if err := s.readSnapshot(filepath.Join(opts.Dir, snapshotFile)); err != nil {
+		return nil, err
+	}
+	replayed, err := s.replayJournal(filepath.Join(opts.Dir, journalFile))
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(filepath.Join(opts.Dir, journalFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	s.journal = &journal{opts: opts, file: file}
+	logger.Info.Printf("Memory storage restored from %s: %d excuse(s), %d journal entries replayed",
+		opts.Dir, len(s.excuses), replayed)
+
+	s.stop = make(chan struct{})
+	s.done.Add(1)
+	go s.background()
+	return s, nil
+}
+
+// readSnapshot загружает снимок, если он есть
+func (s *MemoryStorage) readSnapshot(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snapshot ExcuseFileFormat
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to parse snapshot %s: %w", path, err)
+	}
+	for _, excuse := range snapshot.Excuses {
+		s.put(excuse)
+	}
+	for id, voters := range snapshot.Votes {
+		s.votes[id] = voters
+	}
+	return nil
+}
+
+// replayJournal применяет журнал и возвращает число примененных записей.
+// Незавершенная последняя строка (сбой во время записи) отбрасывается,
+// поврежденная строка в середине - ошибка.
+func (s *MemoryStorage) replayJournal(path string) (int, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	count := 0
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(data) > 0 {
+				logger.Warn.Printf("Discarding incomplete journal entry at line %d of %s", line, path)
+				if err := file.Truncate(offset); err != nil {
+					return count, fmt.Errorf("failed to truncate journal: %w", err)
+				}
+			}
+			return count, nil
+		}
+		if err != nil {
+			return count, fmt.Errorf("failed to read journal: %w", err)
+		}
+
+		var entry journalEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return count, fmt.Errorf("journal %s is corrupt at line %d: %w", path, line, err)
+		}
+		if err := s.apply(entry); err != nil {
+			return count, fmt.Errorf("journal %s line %d: %w", path, line, err)
+		}
+		offset += int64(len(data))
+		count++
+	}
+}
+
+// apply применяет запись журнала к состоянию в памяти
+func (s *MemoryStorage) apply(entry journalEntry) error {
+	switch entry.Op {
+	case opPut:
+		if entry.Excuse == nil {
+			return errors.New("put without excuse")
+		}
+		s.put(*entry.Excuse)
+	case opDelete:
+		s.remove(entry.ID)
+	case opVote:
+		s.applyVote(entry.ID, entry.Voter, entry.Vote)
+	default:
+		return fmt.Errorf("unknown operation %q", entry.Op)
+	}
+	return nil
+}
+
+// record дописывает изменение в журнал, если он включен. Вызывается под
+// s.mu до изменения состояния в памяти.
+func (s *MemoryStorage) record(entry journalEntry) error {
+	if s.journal == nil {
+		return nil
+	}
+	if err := s.journal.append(entry); err != nil {
+		return fmt.Errorf("%w: failed to write journal: %v", ErrUnavailable, err)
+	}
+	return nil
+}
+
+func (j *journal) append(entry journalEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return errors.New("storage is closed")
+	}
+	if _, err := j.file.Write(data); err != nil {
+		return err
+	}
+	if j.opts.Fsync == FsyncAlways {
+		return j.file.Sync()
+	}
+	j.dirty = true
+	return nil
+}
+
+// sync сбрасывает журнал на диск, если в него писали
+func (j *journal) sync() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if !j.dirty || j.file == nil {
+		return nil
+	}
+	j.dirty = false
+	return j.file.Sync()
+}
+
+// Snapshot записывает состояние в снимок и очищает журнал. Снимок
+// пишется во временный файл и атомарно заменяет предыдущий, поэтому при
+// сбое на диске остается либо старый снимок с полным журналом, либо новый.
+func (s *MemoryStorage) Snapshot() error {
+	if s.journal == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshot()
+}
+
+// snapshot - Snapshot под s.mu. После closeJournal ничего не делает.
+func (s *MemoryStorage) snapshot() error {
+	j := s.journal
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return nil
+	}
+
+	snapshot := ExcuseFileFormat{
+		Excuses: make([]models.Excuse, 0, len(s.excuses)),
+		Votes:   s.votes,
+	}
+	for _, excuse := range s.excuses {
+		snapshot.Excuses = append(snapshot.Excuses, excuse)
+	}
+	sort.Slice(snapshot.Excuses, func(i, j int) bool { return snapshot.Excuses[i].ID < snapshot.Excuses[j].ID })
+
+	if err := writeFileAtomic(filepath.Join(j.opts.Dir, snapshotFile), snapshot); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := j.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate journal: %w", err)
+	}
+	j.dirty = false
+	return j.file.Sync()
+}
+
+// writeFileAtomic пишет v как JSON во временный файл рядом с path,
+// сбрасывает его на диск и переименовывает в path.
+func writeFileAtomic(path string, v interface{}) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // после успешного Rename файла уже нет
+
+	enc := json.NewEncoder(tmp)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir сбрасывает на диск каталог, чтобы переименование пережило сбой
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// background делает периодические снимки и fsync в режиме FsyncInterval
+func (s *MemoryStorage) background() {
+	defer s.done.Done()
+
+	var snapshots, syncs <-chan time.Time
+	if s.journal.opts.SnapshotInterval > 0 {
+		ticker := time.NewTicker(s.journal.opts.SnapshotInterval)
+		defer ticker.Stop()
+		snapshots = ticker.C
+	}
+	if s.journal.opts.Fsync == FsyncInterval {
+		ticker := time.NewTicker(fsyncInterval)
+		defer ticker.Stop()
+		syncs = ticker.C
+	}
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-snapshots:
+			if err := s.Snapshot(); err != nil {
+				logger.Error.Printf("Memory storage snapshot failed: %v", err)
+			}
+		case <-syncs:
+			if err := s.journal.sync(); err != nil {
+				logger.Error.Printf("Memory storage journal fsync failed: %v", err)
+			}
+		}
+	}
+}
+
+// closeJournal останавливает фоновые задачи, делает последний снимок и
+// закрывает журнал. Запись после этого возвращает ErrUnavailable, а не
+// меняет только память. Вызывается один раз из Close.
+func (s *MemoryStorage) closeJournal() error {
+	if s.journal == nil {
+		return nil
+	}
+	close(s.stop)
+	s.done.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.snapshot()
+
+	j := s.journal
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if cerr := j.file.Close(); err == nil {
+		err = cerr
+	}
+	j.file = nil
+	return err
+}
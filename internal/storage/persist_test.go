@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"procrastigo/internal/models"
+	"procrastigo/pkg/logger"
+	"testing"
+	"time"
+)
+
+func openPersistent(t *testing.T, dir string) *MemoryStorage {
+	t.Helper()
+	store, err := OpenMemoryStorage(PersistOptions{Dir: dir, Fsync: FsyncAlways})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// mutate выполняет по одной операции каждого вида
+func mutate(t *testing.T, store Storage) {
+	t.Helper()
+	ctx := context.Background()
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	seed(t, store, []models.Excuse{
+		{ID: "a", Text: "one", Category: "work", Language: "en", Severity: "low", CreatedAt: at},
+		{ID: "b", Text: "two", Category: "work", Language: "en", Severity: "low", CreatedAt: at},
+	})
+	if _, err := store.UpdateExcuse(ctx, models.Excuse{ID: "a", Text: "one, edited", Category: "tech", Language: "en", Severity: "high", Tags: []string{"deploy"}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, voter := range []string{"x", "y"} {
+		if _, err := store.RateExcuse(ctx, "a", voter, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.RateExcuse(ctx, "a", "y", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteExcuse(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkMutated(t *testing.T, store Storage) {
+	t.Helper()
+	ctx := context.Background()
+	got, err := store.GetExcuse(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Text != "one, edited" || got.Category != "tech" || got.Rating != 1 || !equalTags(got.Tags, []string{"deploy"}) {
+		t.Errorf("restored %+v", got)
+	}
+	if _, err := store.GetExcuse(ctx, "b"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleted excuse restored: %v", err)
+	}
+	// поисковый индекс восстанавливается вместе с данными
+	hits, err := store.SearchExcuses(ctx, SearchQuery{Text: "edited"})
+	if err != nil || len(hits) != 1 || hits[0].Excuse.ID != "a" {
+		t.Errorf("search index not restored: %v, %v", hits, err)
+	}
+	tags, err := store.GetTags(ctx)
+	if err != nil || len(tags) != 1 || tags[0].Tag != "deploy" || tags[0].Count != 1 {
+		t.Errorf("tags not restored: %v, %v", tags, err)
+	}
+
+	// голос x должен сохраниться: повторный голос не меняет рейтинг
+	rating, err := store.RateExcuse(ctx, "a", "x", 1)
+	if err != nil || rating != 1 {
+		t.Errorf("votes not restored: rating %d, %v", rating, err)
+	}
+}
+
+func TestMemoryPersistenceReplaysJournal(t *testing.T) {
+	logger.Init("production")
+	dir := t.TempDir()
+
+	store := openPersistent(t, dir)
+	mutate(t, store)
+	// имитация аварийной остановки: журнал не сжимается в снимок
+	store.journal.file.Close()
+	close(store.stop)
+	store.done.Wait()
+
+	if _, err := os.Stat(filepath.Join(dir, snapshotFile)); !os.IsNotExist(err) {
+		t.Fatalf("unexpected snapshot before Close: %v", err)
+	}
+
+	restored := openPersistent(t, dir)
+	defer restored.Close()
+	checkMutated(t, restored)
+}
+
+func TestMemoryPersistenceSnapshot(t *testing.T) {
+	logger.Init("production")
+	dir := t.TempDir()
+
+	store := openPersistent(t, dir)
+	mutate(t, store)
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	// запись после Close не должна молча пропасть
+	if _, err := store.RateExcuse(context.Background(), "a", "z", 1); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("write after Close: got %v, want ErrUnavailable", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, journalFile))
+	if err != nil || info.Size() != 0 {
+		t.Fatalf("journal must be empty after snapshot: %v, %v", info, err)
+	}
+
+	restored := openPersistent(t, dir)
+	defer restored.Close()
+	checkMutated(t, restored)
+}
+
+func TestMemoryPersistenceDiscardsTornEntry(t *testing.T) {
+	logger.Init("production")
+	dir := t.TempDir()
+
+	store := openPersistent(t, dir)
+	mutate(t, store)
+	store.journal.file.WriteString(`{"op":"delete","id":"a"`) // запись оборвалась
+	store.journal.file.Close()
+	close(store.stop)
+	store.done.Wait()
+
+	restored := openPersistent(t, dir)
+	checkMutated(t, restored)
+
+	// после отбрасывания хвоста журнал снова пригоден для записи
+	seed(t, restored, []models.Excuse{{ID: "c", Text: "three", Category: "work", Language: "en", Severity: "low"}})
+	restored.journal.file.Close()
+	close(restored.stop)
+	restored.done.Wait()
+
+	again := openPersistent(t, dir)
+	defer again.Close()
+	if _, err := again.GetExcuse(context.Background(), "c"); err != nil {
+		t.Errorf("excuse written after recovery lost: %v", err)
+	}
+}
+
+func TestMemoryPersistenceRejectsCorruptJournal(t *testing.T) {
+	logger.Init("production")
+	dir := t.TempDir()
+	content := "not json\n" + `{"op":"delete","id":"a"}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, journalFile), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenMemoryStorage(PersistOptions{Dir: dir, Fsync: FsyncAlways}); err == nil {
+		t.Error("corrupt journal accepted")
+	}
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"procrastigo/internal/models"
+	"procrastigo/internal/search"
+)
+
+// defaultSearchLimit - сколько результатов возвращает поиск без Limit
+const defaultSearchLimit = 20
+
+// SearchQuery - запрос полнотекстового поиска
+type SearchQuery struct {
+	Text   string       // слова для поиска; стоп-слова не учитываются
+	Filter ExcuseFilter // дополнительные условия, как у списка
+	Limit  int          // <= 0 - defaultSearchLimit
+}
+
+// terms возвращает основы слов запроса или ErrInvalid, если их нет
+func (q SearchQuery) terms() ([]string, error) {
+	terms := search.Terms(q.Text)
+	if len(terms) == 0 {
+		return nil, Invalidf("search query %q has no searchable words", q.Text)
+	}
+	return terms, nil
+}
+
+func (q SearchQuery) limit() int {
+	if q.Limit <= 0 {
+		return defaultSearchLimit
+	}
+	return q.Limit
+}
+
+// searchHits превращает первые limit совпадений индекса в результаты
+// с подсветкой
+func searchHits(matches []search.Match, terms []string, limit int, get func(id string) models.Excuse) []models.SearchHit {
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	hits := make([]models.SearchHit, 0, len(matches))
+	for _, match := range matches {
+		excuse := get(match.ID)
+		hits = append(hits, models.SearchHit{
+			Excuse:    excuse,
+			Score:     match.Score,
+			Highlight: search.Highlight(excuse.Text, terms),
+		})
+	}
+	return hits
+}
@@ -0,0 +1,730 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"procrastigo/internal/dedup"
+	"procrastigo/internal/models"
+	"procrastigo/internal/search"
+	"procrastigo/pkg/utils"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqlDialect описывает различия SQL бэкендов. Сами запросы (в том числе
+// sqlWhere и sqlOrder с плейсхолдерами $N) общие.
+type sqlDialect interface {
+	// name - имя диалекта для migrations.New
+	name() string
+	// bind приводит аргумент запроса к виду, в котором его хранит бэкенд
+	bind(v interface{}) interface{}
+	// lockRow - суффикс SELECT, блокирующий строку до конца транзакции
+	lockRow() string
+	// byteOrder - выражение, сравнивающее строковую колонку побайтно, как
+	// MemoryStorage, а не по правилам collation базы
+	byteOrder(column string) string
+}
+
+// sqlStorage реализует Storage поверх database/sql. PostgresStorage и
+// SQLiteStorage встраивают его со своим диалектом.
+type sqlStorage struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// args пропускает аргументы запроса через dialect.bind
+func (s *sqlStorage) args(args ...interface{}) []interface{} {
+	for i, arg := range args {
+		args[i] = s.dialect.bind(arg)
+	}
+	return args
+}
+
+// LoadFromFile загружает файл (см. ReadExcuseFile) одной транзакцией:
+// новые id вставляются, у существующих обновляются text, category,
+// language, severity и теги. Повторная загрузка того же файла ничего не меняет.
+func (s *sqlStorage) LoadFromFile(ctx context.Context, filename string, opts LoadOptions) (*LoadReport, error) {
+	excuses, report, err := ReadExcuseFile(filename, opts)
+	if err != nil {
+		return report, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return report, fmt.Errorf("failed to begin transaction: %w", dbErr(ctx, err))
+	}
+	defer tx.Rollback()
+
+	exists, err := tx.PrepareContext(ctx, "SELECT COUNT(*) FROM excuses WHERE id = $1")
+	if err != nil {
+		return report, fmt.Errorf("failed to prepare lookup: %w", dbErr(ctx, err))
+	}
+	defer exists.Close()
+
+	// если поля совпадают, WHERE отбрасывает обновление и запрос не
+	// возвращает строк; статус модерации задается только при вставке
+	upsert, err := tx.PrepareContext(ctx, `
+    INSERT INTO excuses (id, text, category, language, severity, created_at, rating, rand_key, status, rejection_reason)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+    ON CONFLICT (id) DO UPDATE
+    SET text = EXCLUDED.text, category = EXCLUDED.category,
+        language = EXCLUDED.language, severity = EXCLUDED.severity
+    WHERE (excuses.text, excuses.category, excuses.language, excuses.severity)
+        IS DISTINCT FROM (EXCLUDED.text, EXCLUDED.category, EXCLUDED.language, EXCLUDED.severity)
+    RETURNING id`)
+	if err != nil {
+		return report, fmt.Errorf("failed to prepare upsert: %w", dbErr(ctx, err))
+	}
+	defer upsert.Close()
+
+	var inserted, updated, unchanged int
+	for _, excuse := range excuses {
+		var count int
+		if err := exists.QueryRowContext(ctx, excuse.ID).Scan(&count); err != nil {
+			return report, fmt.Errorf("failed to look up excuse %s: %w", excuse.ID, dbErr(ctx, err))
+		}
+
+		var id string
+		err := upsert.QueryRowContext(ctx, s.args(
+			excuse.ID, excuse.Text, excuse.Category, excuse.Language,
+			excuse.Severity, excuse.CreatedAt, excuse.Rating, utils.RandomFloat(),
+			statusOrDefault(excuse.Status), nullString(excuse.RejectionReason),
+		)...).Scan(&id)
+		rowChanged := err == nil
+		if err != nil && err != sql.ErrNoRows {
+			return report, fmt.Errorf("failed to upsert excuse %s: %w", excuse.ID, dbErr(ctx, err))
+		}
+
+		tags, err := excuseTags(ctx, tx, excuse.ID)
+		if err != nil {
+			return report, fmt.Errorf("failed to read tags of excuse %s: %w", excuse.ID, dbErr(ctx, err))
+		}
+		tagsChanged := !equalTags(tags, excuse.Tags)
+		if tagsChanged {
+			if err := replaceTags(ctx, tx, excuse.ID, excuse.Tags); err != nil {
+				return report, fmt.Errorf("failed to save tags of excuse %s: %w", excuse.ID, dbErr(ctx, err))
+			}
+		}
+
+		switch {
+		case !rowChanged && !tagsChanged:
+			unchanged++
+		case count == 0:
+			inserted++
+		default:
+			updated++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return report, fmt.Errorf("failed to commit seed: %w", dbErr(ctx, err))
+	}
+	report.Inserted, report.Updated, report.Unchanged = inserted, updated, unchanged
+	report.Loaded = len(excuses)
+	return report, nil
+}
+
+// excuseColumns - колонки, которые читает scanExcuse, в том же порядке.
+// Теги собираются подзапросом в строку через запятую (в теге запятых нет).
+const excuseColumns = "id, text, category, language, severity, created_at, rating, status, rejection_reason, " +
+	"(SELECT string_agg(tag, ',') FROM excuse_tags WHERE excuse_tags.excuse_id = excuses.id)"
+
+// rowScanner - общее у *sql.Row и *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanExcuse читает строку, выбранную с колонками excuseColumns; extra -
+// приемники для колонок, перечисленных после них
+func scanExcuse(row rowScanner, extra ...interface{}) (models.Excuse, error) {
+	var excuse models.Excuse
+	var reason, tags sql.NullString
+	// Обязательно сканируем все поля, включая Rating
+	dest := append([]interface{}{&excuse.ID, &excuse.Text, &excuse.Category, &excuse.Language,
+		&excuse.Severity, &excuse.CreatedAt, &excuse.Rating, &excuse.Status, &reason, &tags}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return excuse, err
+	}
+	excuse.RejectionReason = reason.String
+	if tags.String != "" {
+		excuse.Tags = strings.Split(tags.String, ",")
+		sort.Strings(excuse.Tags)
+	}
+	return excuse, nil
+}
+
+// nullString сохраняет пустую строку как NULL
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// excuseTags читает теги оправдания по алфавиту
+func excuseTags(ctx context.Context, tx *sql.Tx, id string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT tag FROM excuse_tags WHERE excuse_id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags, rows.Err()
+}
+
+// replaceTags заменяет теги оправдания на tags
+func replaceTags(ctx context.Context, tx *sql.Tx, id string, tags []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM excuse_tags WHERE excuse_id = $1", id); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO excuse_tags (excuse_id, tag) VALUES ($1, $2)", id, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetRandomExcuse получает случайное оправдание из БД.
+// Вместо ORDER BY RANDOM(), который сортирует всю выборку, подходящие строки
+// считаются и берется строка со случайным смещением в порядке индекса
+// rand_key: так у каждой строки одинаковый шанс.
+func (s *sqlStorage) GetRandomExcuse(ctx context.Context, filter ExcuseFilter) (*models.Excuse, error) {
+	where, args := filter.sqlWhere(1)
+	if where != "" {
+		where = " WHERE " + where
+	}
+	args = s.args(args...)
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM excuses"+where, args...).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to count excuses: %w", dbErr(ctx, err))
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("random excuse: %w", ErrNotFound)
+	}
+
+	query := "SELECT " + excuseColumns + " FROM excuses" + where +
+		fmt.Sprintf(" ORDER BY rand_key LIMIT 1 OFFSET $%d", len(args)+1)
+	excuse, err := scanExcuse(s.db.QueryRowContext(ctx, query, append(args, utils.RandomInt(count))...))
+	if err == sql.ErrNoRows {
+		// строки удалили между запросами - берем первую из оставшихся
+		excuse, err = scanExcuse(s.db.QueryRowContext(ctx, query, append(args, 0)...))
+	}
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("random excuse: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan random excuse: %w", dbErr(ctx, err))
+	}
+
+	return &excuse, nil
+}
+
+// GetExcuses получает страницу оправданий с фильтрацией и сортировкой
+func (s *sqlStorage) GetExcuses(ctx context.Context, filter ExcuseFilter, page PageRequest) (*models.ExcusePage, error) {
+	sortBy := page.sortOrDefault()
+	if !ValidSort(sortBy) {
+		return nil, Invalidf("unknown sort %q", sortBy)
+	}
+	after, err := decodeCursor(page.Cursor, sortBy)
+	if err != nil {
+		return nil, err
+	}
+
+	// Динамическое построение WHERE части запроса
+	where, args := filter.sqlWhere(1)
+	orderBy, afterWhere, afterArgs := sqlOrder(s.dialect, sortBy, after, len(args)+1)
+	if afterWhere != "" {
+		if where != "" {
+			where += " AND "
+		}
+		where += afterWhere
+		args = append(args, afterArgs...)
+	}
+
+	// Собираем запрос
+	sqlQuery := "SELECT " + excuseColumns + " FROM excuses"
+	if where != "" {
+		sqlQuery += " WHERE " + where
+	}
+	sqlQuery += " ORDER BY " + orderBy
+
+	// Берем на одну строку больше, чтобы узнать, есть ли следующая страница
+	if page.Limit > 0 {
+		sqlQuery += fmt.Sprintf(" LIMIT $%d", len(args)+1)
+		args = append(args, page.Limit+1)
+	}
+
+	rows, err := s.db.QueryContext(ctx, sqlQuery, s.args(args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query excuses: %w", dbErr(ctx, err))
+	}
+	defer rows.Close()
+
+	var excuses []models.Excuse
+	for rows.Next() {
+		excuse, err := scanExcuse(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan excuse row: %w", dbErr(ctx, err))
+		}
+		excuses = append(excuses, excuse)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate excuses: %w", dbErr(ctx, err))
+	}
+
+	return buildPage(excuses, sortBy, page.Limit), nil
+}
+
+// CreateExcuse создает новое оправдание вместе с тегами
+func (s *sqlStorage) CreateExcuse(ctx context.Context, excuse models.Excuse) error {
+	query := `
+    INSERT INTO excuses (id, text, category, language, severity, created_at, rating, rand_key, status, rejection_reason)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", dbErr(ctx, err))
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, query, s.args(
+		excuse.ID,
+		excuse.Text,
+		excuse.Category,
+		excuse.Language,
+		excuse.Severity,
+		excuse.CreatedAt,
+		excuse.Rating, // <--- Вставляем рейтинг
+		utils.RandomFloat(),
+		statusOrDefault(excuse.Status),
+		nullString(excuse.RejectionReason),
+	)...)
+	if err != nil {
+		return fmt.Errorf("failed to insert excuse: %w", dbErr(ctx, err))
+	}
+	if err := replaceTags(ctx, tx, excuse.ID, excuse.Tags); err != nil {
+		return fmt.Errorf("failed to save tags: %w", dbErr(ctx, err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit excuse: %w", dbErr(ctx, err))
+	}
+	return nil
+}
+
+// GetExcuse получает оправдание по id
+func (s *sqlStorage) GetExcuse(ctx context.Context, id string) (*models.Excuse, error) {
+	query := "SELECT " + excuseColumns + " FROM excuses WHERE id = $1"
+
+	excuse, err := scanExcuse(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("excuse %s: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get excuse: %w", dbErr(ctx, err))
+	}
+	return &excuse, nil
+}
+
+// UpdateExcuse заменяет редактируемые поля и теги оправдания
+func (s *sqlStorage) UpdateExcuse(ctx context.Context, excuse models.Excuse) (*models.Excuse, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", dbErr(ctx, err))
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+    UPDATE excuses
+    SET text = $2, category = $3, language = $4, severity = $5
+    WHERE id = $1`,
+		excuse.ID, excuse.Text, excuse.Category, excuse.Language, excuse.Severity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update excuse: %w", dbErr(ctx, err))
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return nil, fmt.Errorf("excuse %s: %w", excuse.ID, ErrNotFound)
+	}
+	if err := replaceTags(ctx, tx, excuse.ID, excuse.Tags); err != nil {
+		return nil, fmt.Errorf("failed to save tags: %w", dbErr(ctx, err))
+	}
+
+	updated, err := scanExcuse(tx.QueryRowContext(ctx, "SELECT "+excuseColumns+" FROM excuses WHERE id = $1", excuse.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read updated excuse: %w", dbErr(ctx, err))
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit excuse: %w", dbErr(ctx, err))
+	}
+	return &updated, nil
+}
+
+// ModerateExcuse меняет статус оправдания, если оно еще на модерации.
+// Условие на статус в UPDATE не дает двум модераторам принять решение дважды.
+func (s *sqlStorage) ModerateExcuse(ctx context.Context, id, status, reason string) (*models.Excuse, error) {
+	if err := checkDecision(status, reason); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", dbErr(ctx, err))
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+    UPDATE excuses
+    SET status = $2, rejection_reason = $3
+    WHERE id = $1 AND status = $4`,
+		s.args(id, status, nullString(reason), models.StatusPending)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to moderate excuse: %w", dbErr(ctx, err))
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	excuse, err := scanExcuse(tx.QueryRowContext(ctx, "SELECT "+excuseColumns+" FROM excuses WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("excuse %s: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read moderated excuse: %w", dbErr(ctx, err))
+	}
+	if rowsAffected == 0 {
+		return nil, fmt.Errorf("excuse %s is %s, not pending: %w", id, excuse.Status, ErrConflict)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit moderation: %w", dbErr(ctx, err))
+	}
+	return &excuse, nil
+}
+
+// DeleteExcuse удаляет оправдание; голоса удаляются каскадно
+func (s *sqlStorage) DeleteExcuse(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, "DELETE FROM excuses WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete excuse: %w", dbErr(ctx, err))
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("excuse %s: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// RateExcuse сохраняет голос и пересчитывает рейтинг из таблицы votes.
+// Строка оправдания блокируется, чтобы параллельные голоса не потеряли пересчет.
+func (s *sqlStorage) RateExcuse(ctx context.Context, id, voterID string, vote int) (int, error) {
+	if vote < -1 || vote > 1 {
+		return 0, Invalidf("vote must be -1, 0 or 1, got %d", vote)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", dbErr(ctx, err))
+	}
+	defer tx.Rollback()
+
+	var locked string
+	err = tx.QueryRowContext(ctx, "SELECT id FROM excuses WHERE id = $1"+s.dialect.lockRow(), id).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("excuse %s: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock excuse: %w", dbErr(ctx, err))
+	}
+
+	if vote == 0 {
+		_, err = tx.ExecContext(ctx, "DELETE FROM votes WHERE excuse_id = $1 AND voter_id = $2", id, voterID)
+	} else {
+		_, err = tx.ExecContext(ctx, `
+    INSERT INTO votes (excuse_id, voter_id, value, updated_at)
+    VALUES ($1, $2, $3, $4)
+    ON CONFLICT (excuse_id, voter_id) DO UPDATE
+    SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at`,
+			s.args(id, voterID, vote, time.Now().UTC())...)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to save vote: %w", dbErr(ctx, err))
+	}
+
+	var rating int
+	err = tx.QueryRowContext(ctx, `
+    UPDATE excuses
+    SET rating = (SELECT COALESCE(SUM(value), 0) FROM votes WHERE excuse_id = $1)
+    WHERE id = $1
+    RETURNING rating`, id).Scan(&rating)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update excuse rating: %w", dbErr(ctx, err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit vote: %w", dbErr(ctx, err))
+	}
+	return rating, nil
+}
+
+func (s *sqlStorage) GetVotes(ctx context.Context, id string) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT voter_id, value FROM votes WHERE excuse_id = $1", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query votes: %w", dbErr(ctx, err))
+	}
+	defer rows.Close()
+
+	votes := make(map[string]int)
+	for rows.Next() {
+		var voter string
+		var vote int
+		if err := rows.Scan(&voter, &vote); err != nil {
+			return nil, fmt.Errorf("failed to scan vote: %w", dbErr(ctx, err))
+		}
+		votes[voter] = vote
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read votes: %w", dbErr(ctx, err))
+	}
+	if len(votes) == 0 {
+		// без голосов нужно отличить оправдание без голосов от отсутствующего
+		if _, err := s.GetExcuse(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	return votes, nil
+}
+
+// SearchExcuses - поиск без полнотекстового индекса в базе: подходящие
+// под фильтр строки ранжируются в приложении тем же индексом, что и в
+// MemoryStorage. PostgresStorage заменяет его поиском по tsvector.
+func (s *sqlStorage) SearchExcuses(ctx context.Context, query SearchQuery) ([]models.SearchHit, error) {
+	terms, err := query.terms()
+	if err != nil {
+		return nil, err
+	}
+
+	where, args := query.Filter.sqlWhere(1)
+	sqlQuery := "SELECT " + excuseColumns + " FROM excuses"
+	if where != "" {
+		sqlQuery += " WHERE " + where
+	}
+	rows, err := s.db.QueryContext(ctx, sqlQuery, s.args(args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search excuses: %w", dbErr(ctx, err))
+	}
+	defer rows.Close()
+
+	index := search.NewIndex()
+	excuses := make(map[string]models.Excuse)
+	for rows.Next() {
+		excuse, err := scanExcuse(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan excuse: %w", dbErr(ctx, err))
+		}
+		excuses[excuse.ID] = excuse
+		index.Add(excuse.ID, excuse.Text)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate excuses: %w", dbErr(ctx, err))
+	}
+
+	return searchHits(index.Search(terms, nil), terms, query.limit(), func(id string) models.Excuse {
+		return excuses[id]
+	}), nil
+}
+
+// FindSimilar - поиск похожих без поддержки в базе: text сравнивается с
+// опубликованными оправданиями в приложении. PostgresStorage использует pg_trgm.
+func (s *sqlStorage) FindSimilar(ctx context.Context, text string, threshold float64, limit int) ([]models.SimilarExcuse, error) {
+	if err := checkThreshold(threshold); err != nil {
+		return nil, err
+	}
+	excuses, err := s.allExcuses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return similarExcuses(text, threshold, limit, excuses, textTrigrams), nil
+}
+
+// DuplicateClusters сравнивает все оправдания попарно в приложении
+func (s *sqlStorage) DuplicateClusters(ctx context.Context, threshold float64) ([]models.DuplicateCluster, error) {
+	if err := checkThreshold(threshold); err != nil {
+		return nil, err
+	}
+	excuses, err := s.allExcuses(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]models.Excuse, len(excuses))
+	for _, excuse := range excuses {
+		byID[excuse.ID] = excuse
+	}
+	pairs := similarPairs(excuses, threshold, textTrigrams)
+	return duplicateClusters(pairs, func(id string) models.Excuse { return byID[id] }), nil
+}
+
+func textTrigrams(excuse models.Excuse) dedup.Trigrams {
+	return dedup.NewTrigrams(excuse.Text)
+}
+
+// allExcuses читает все оправдания по id
+func (s *sqlStorage) allExcuses(ctx context.Context) ([]models.Excuse, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+excuseColumns+" FROM excuses ORDER BY "+s.dialect.byteOrder("id"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query excuses: %w", dbErr(ctx, err))
+	}
+	defer rows.Close()
+
+	var excuses []models.Excuse
+	for rows.Next() {
+		excuse, err := scanExcuse(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan excuse row: %w", dbErr(ctx, err))
+		}
+		excuses = append(excuses, excuse)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate excuses: %w", dbErr(ctx, err))
+	}
+	return excuses, nil
+}
+
+// GetTags считает одобренные оправдания с каждым тегом
+func (s *sqlStorage) GetTags(ctx context.Context) ([]models.TagCount, error) {
+	rows, err := s.db.QueryContext(ctx, `
+    SELECT tag, COUNT(*) FROM excuse_tags
+    JOIN excuses ON excuses.id = excuse_tags.excuse_id
+    WHERE excuses.status = $1
+    GROUP BY tag`, models.StatusApproved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", dbErr(ctx, err))
+	}
+	defer rows.Close()
+
+	counts := []models.TagCount{}
+	for rows.Next() {
+		var count models.TagCount
+		if err := rows.Scan(&count.Tag, &count.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan tag row: %w", dbErr(ctx, err))
+		}
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate tags: %w", dbErr(ctx, err))
+	}
+	sortTagCounts(counts)
+	return counts, nil
+}
+
+// GetStats вычисляет и возвращает статистику
+func (s *sqlStorage) GetStats(ctx context.Context) (*models.Stats, error) {
+	stats := &models.Stats{}
+	approved := models.StatusApproved
+
+	// 1. Общее количество
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM excuses WHERE status = $1", approved).Scan(&stats.TotalExcuses); err != nil {
+		return nil, fmt.Errorf("failed to get total excuses: %w", dbErr(ctx, err))
+	}
+
+	// 2. Самая популярная категория (используем COALESCE для случая, когда нет данных)
+	var mostPopular sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+    SELECT category FROM excuses
+    WHERE status = $1
+    GROUP BY category
+    ORDER BY COUNT(*) DESC, category
+    LIMIT 1`, approved).Scan(&mostPopular)
+
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get most popular category: %w", dbErr(ctx, err))
+	}
+	stats.MostPopularCategory = mostPopular.String
+
+	// 3. Оправдания за сегодня (сутки по UTC)
+	today := utils.GetStartOfDay()
+	if err := s.db.QueryRowContext(ctx, `
+    SELECT COUNT(*) FROM excuses
+    WHERE created_at >= $1 AND created_at < $2 AND status = $3`, s.args(today, today.Add(24*time.Hour), approved)...).Scan(&stats.ExcusesToday); err != nil {
+		return nil, fmt.Errorf("failed to get excuses today: %w", dbErr(ctx, err))
+	}
+
+	// 4. Уровень прокрастинации
+	stats.GlobalProcrastinationLevel = utils.CalculateProcrastinationLevel(stats.ExcusesToday)
+
+	return stats, nil
+}
+
+// Close закрывает пул соединений с базой данных
+func (s *sqlStorage) Close() error {
+	return s.db.Close()
+}
+
+// dbErr приводит ошибку драйвера к категориям хранилища. Если запрос был
+// прерван отменой или таймаутом, возвращается ошибка контекста: драйвер
+// в этом случае сообщает об отмене запроса сервером, а вызывающему коду
+// нужна context.Canceled / context.DeadlineExceeded.
+func dbErr(ctx context.Context, err error) error {
+	if cerr := ctx.Err(); cerr != nil {
+		return cerr
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == "23505": // unique_violation
+			return fmt.Errorf("%w: %v", ErrConflict, err)
+		case pqErr.Code.Class() == "22", pqErr.Code.Class() == "23": // data_exception, integrity_constraint_violation
+			return fmt.Errorf("%w: %v", ErrInvalid, err)
+		case pqErr.Code.Class() == "08", pqErr.Code.Class() == "53", pqErr.Code.Class() == "57": // connection, resources, operator intervention
+			return fmt.Errorf("%w: %v", ErrUnavailable, err)
+		}
+		return err
+	}
+
+	var liteErr *sqlite.Error
+	if errors.As(err, &liteErr) {
+		switch code := liteErr.Code(); {
+		case code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY, code == sqlite3.SQLITE_CONSTRAINT_UNIQUE:
+			return fmt.Errorf("%w: %v", ErrConflict, err)
+		case code&0xff == sqlite3.SQLITE_CONSTRAINT, code&0xff == sqlite3.SQLITE_MISMATCH, code&0xff == sqlite3.SQLITE_TOOBIG:
+			return fmt.Errorf("%w: %v", ErrInvalid, err)
+		case code&0xff == sqlite3.SQLITE_BUSY, code&0xff == sqlite3.SQLITE_LOCKED, code&0xff == sqlite3.SQLITE_IOERR,
+			code&0xff == sqlite3.SQLITE_FULL, code&0xff == sqlite3.SQLITE_CANTOPEN, code&0xff == sqlite3.SQLITE_READONLY:
+			return fmt.Errorf("%w: %v", ErrUnavailable, err)
+		}
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return err
+}
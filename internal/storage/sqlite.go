@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"procrastigo/internal/storage/migrations"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteStorage реализует Storage во встроенной базе SQLite (один файл,
+// без отдельного сервера). Запросы и миграции общие с PostgresStorage.
+type SQLiteStorage struct {
+	sqlStorage
+}
+
+// sqliteTime - формат хранения времени: UTC и фиксированная длина, чтобы
+// строки сравнивались в SQL так же, как моменты времени
+const sqliteTime = "2006-01-02T15:04:05.000000000Z"
+
+// sqliteDialect - диалект SQLite. FOR UPDATE не нужен: транзакции
+// открываются как BEGIN IMMEDIATE и сразу берут блокировку записи.
+type sqliteDialect struct{}
+
+func (sqliteDialect) name() string    { return migrations.DialectSQLite }
+func (sqliteDialect) lockRow() string { return "" }
+
+// byteOrder: BINARY - collation SQLite по умолчанию - уже сравнивает байты
+func (sqliteDialect) byteOrder(column string) string { return column }
+
+func (sqliteDialect) bind(v interface{}) interface{} {
+	if t, ok := v.(time.Time); ok {
+		return t.UTC().Format(sqliteTime)
+	}
+	return v
+}
+
+// NewSQLiteStorage открывает (или создает) базу в файле path.
+// Если autoMigrate включен, ожидающие миграции применяются сразу.
+func NewSQLiteStorage(ctx context.Context, path string, autoMigrate bool) (*SQLiteStorage, error) {
+	db, err := openSQLite(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	dialect := sqliteDialect{}
+	if err := prepareSchema(ctx, db, dialect, autoMigrate); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteStorage{sqlStorage{db: db, dialect: dialect}}, nil
+}
+
+// openSQLite открывает файл базы с журналом WAL, внешними ключами (для
+// каскадного удаления голосов) и ожиданием блокировки вместо ошибки SQLITE_BUSY.
+func openSQLite(ctx context.Context, path string) (*sql.DB, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Set("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open database %s: %w: %v", path, ErrUnavailable, err)
+	}
+	return db, nil
+}
+
+var _ Storage = (*SQLiteStorage)(nil)
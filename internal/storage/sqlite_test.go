@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"procrastigo/internal/storage/migrations"
+	"procrastigo/pkg/logger"
+	"testing"
+)
+
+func TestSQLiteKeepsDataAfterReopen(t *testing.T) {
+	logger.Init("production")
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "data", "procrastigo.db")
+
+	store, err := NewSQLiteStorage(ctx, path, true)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage: %v", err)
+	}
+	mutate(t, store)
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := NewSQLiteStorage(ctx, path, false)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer restored.Close()
+	checkMutated(t, restored)
+}
+
+func TestSQLiteMigrationsRoundTrip(t *testing.T) {
+	logger.Init("production")
+	ctx := context.Background()
+
+	db, err := openSQLite(ctx, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	migrator, err := migrations.New(db, migrations.DialectSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied, err := migrator.Up(ctx)
+	if err != nil || applied == 0 {
+		t.Fatalf("Up: applied %d, %v", applied, err)
+	}
+	if _, err := migrator.Down(ctx, applied); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if pending, err := migrator.Pending(ctx); err != nil || pending != applied {
+		t.Fatalf("Pending after Down: %d, %v; want %d", pending, err, applied)
+	}
+}
@@ -1,12 +1,53 @@
 package storage
 
-import "procrastigo/internal/models"
+import (
+	"context"
+	"procrastigo/internal/models"
+)
 
+// Storage - хранилище оправданий. Все методы принимают контекст запроса:
+// при его отмене или истечении дедлайна операция прерывается и возвращает
+// ошибку, для которой errors.Is(err, ctx.Err()) истинно.
 type Storage interface {
-	GetRandomExcuse() (*models.Excuse, error)
-	GetExcuses(category, language string, limit int) ([]models.Excuse, error)
-	RateExcuse(id string, change int) error
-	CreateExcuse(excuse models.Excuse) error
-	GetStats() (*models.Stats, error)
-	LoadFromFile(filename string) error
+	// GetRandomExcuse возвращает случайное оправдание из подходящих под
+	// фильтр или ErrNotFound, если таких нет.
+	GetRandomExcuse(ctx context.Context, filter ExcuseFilter) (*models.Excuse, error)
+	GetExcuses(ctx context.Context, filter ExcuseFilter, page PageRequest) (*models.ExcusePage, error)
+	// RateExcuse записывает голос voterID за оправдание: 1, -1 или 0 (отозвать)
+	// и возвращает рейтинг, пересчитанный как сумма всех голосов.
+	RateExcuse(ctx context.Context, id, voterID string, vote int) (int, error)
+	// GetVotes возвращает голоса за оправдание: голосующий -> голос.
+	GetVotes(ctx context.Context, id string) (map[string]int, error)
+	CreateExcuse(ctx context.Context, excuse models.Excuse) error
+	GetExcuse(ctx context.Context, id string) (*models.Excuse, error)
+	// UpdateExcuse заменяет редактируемые поля (text, category, language,
+	// severity, tags) и возвращает сохраненное оправдание. id, created_at и
+	// rating не меняются.
+	UpdateExcuse(ctx context.Context, excuse models.Excuse) (*models.Excuse, error)
+	DeleteExcuse(ctx context.Context, id string) error
+	// ModerateExcuse принимает решение по оправданию в статусе pending:
+	// approved или rejected с причиной reason. Для оправдания не на
+	// модерации возвращает ErrConflict.
+	ModerateExcuse(ctx context.Context, id, status, reason string) (*models.Excuse, error)
+	// GetStats считает только одобренные оправдания, как публичные выборки.
+	GetStats(ctx context.Context) (*models.Stats, error)
+	// GetTags возвращает теги одобренных оправданий с их числом по
+	// убыванию числа, при равенстве - по алфавиту.
+	GetTags(ctx context.Context) ([]models.TagCount, error)
+	// SearchExcuses ищет оправдания, текст которых содержит все слова
+	// запроса (с учетом словоформ), и возвращает их по убыванию
+	// релевантности. Запрос без значимых слов - ErrInvalid.
+	SearchExcuses(ctx context.Context, query SearchQuery) ([]models.SearchHit, error)
+	// FindSimilar возвращает до limit опубликованных оправданий, сходство
+	// текста которых с text (см. пакет dedup) не меньше threshold, по
+	// убыванию сходства. Ожидающие модерации и отклоненные не учитываются.
+	FindSimilar(ctx context.Context, text string, threshold float64, limit int) ([]models.SimilarExcuse, error)
+	// DuplicateClusters группирует оправдания, попарно похожие не меньше
+	// чем на threshold; оправдания без пары не возвращаются.
+	DuplicateClusters(ctx context.Context, threshold float64) ([]models.DuplicateCluster, error)
+	// LoadFromFile загружает файл в формате ExcuseFileFormat, проверяя
+	// записи согласно opts (см. ReadExcuseFile), и возвращает отчет.
+	// Отчет возвращается и вместе с *LoadError.
+	LoadFromFile(ctx context.Context, filename string, opts LoadOptions) (*LoadReport, error)
+	Close() error
 }
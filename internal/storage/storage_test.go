@@ -0,0 +1,19 @@
+package storage
+
+import (
+	"context"
+	"procrastigo/internal/models"
+	"testing"
+)
+
+// Общие для всех бэкендов тесты лежат в пакете storagetest
+// (см. conformance_test.go); здесь - тесты отдельных реализаций.
+
+func seed(t *testing.T, store Storage, excuses []models.Excuse) {
+	t.Helper()
+	for _, excuse := range excuses {
+		if err := store.CreateExcuse(context.Background(), excuse); err != nil {
+			t.Fatalf("CreateExcuse(%s): %v", excuse.ID, err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"procrastigo/internal/models"
+	"procrastigo/internal/storage"
+	"testing"
+)
+
+func testCRUD(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	original := models.Excuse{ID: "c1", Text: "typo", Category: "work", Language: "en", Severity: "low", CreatedAt: Day(1)}
+	Seed(t, store, []models.Excuse{original})
+
+	if _, err := store.RateExcuse(ctx, "c1", "voter", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.GetExcuse(ctx, "c1")
+	if err != nil {
+		t.Fatalf("GetExcuse: %v", err)
+	}
+	if got.Text != "typo" || got.Rating != 1 || !got.CreatedAt.Equal(Day(1)) {
+		t.Fatalf("GetExcuse: got %+v", got)
+	}
+
+	updated, err := store.UpdateExcuse(ctx, models.Excuse{
+		ID: "c1", Text: "fixed", Category: "tech", Language: "ru", Severity: "high",
+		CreatedAt: Day(9), Rating: 100,
+	})
+	if err != nil {
+		t.Fatalf("UpdateExcuse: %v", err)
+	}
+	if updated.Text != "fixed" || updated.Category != "tech" || updated.Language != "ru" || updated.Severity != "high" {
+		t.Fatalf("UpdateExcuse did not change editable fields: %+v", updated)
+	}
+	if updated.Rating != 1 || !updated.CreatedAt.Equal(Day(1)) {
+		t.Fatalf("UpdateExcuse changed rating or created_at: %+v", updated)
+	}
+
+	if err := store.DeleteExcuse(ctx, "c1"); err != nil {
+		t.Fatalf("DeleteExcuse: %v", err)
+	}
+	if _, err := store.GetExcuse(ctx, "c1"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetExcuse after delete: got %v, want ErrNotFound", err)
+	}
+	if err := store.DeleteExcuse(ctx, "c1"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("DeleteExcuse twice: got %v, want ErrNotFound", err)
+	}
+	if _, err := store.UpdateExcuse(ctx, original); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("UpdateExcuse after delete: got %v, want ErrNotFound", err)
+	}
+
+	// голоса удаленного оправдания не достаются новому с тем же id
+	Seed(t, store, []models.Excuse{original})
+	rating, err := store.RateExcuse(ctx, "c1", "other", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rating != 1 {
+		t.Fatalf("rating after re-create: got %d, want 1", rating)
+	}
+}
+
+func testErrorCategories(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+
+	if _, err := store.GetRandomExcuse(ctx, storage.ExcuseFilter{}); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetRandomExcuse on empty storage: got %v, want ErrNotFound", err)
+	}
+	if _, err := store.GetExcuse(ctx, "missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetExcuse missing: got %v, want ErrNotFound", err)
+	}
+	if _, err := store.UpdateExcuse(ctx, models.Excuse{ID: "missing", Text: "x"}); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("UpdateExcuse missing: got %v, want ErrNotFound", err)
+	}
+
+	excuse := models.Excuse{ID: "e1", Text: "dup", Category: "work", Language: "en", Severity: "low", CreatedAt: Day(1)}
+	Seed(t, store, []models.Excuse{excuse})
+
+	if err := store.CreateExcuse(ctx, excuse); !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("CreateExcuse duplicate: got %v, want ErrConflict", err)
+	}
+	if _, err := store.RateExcuse(ctx, "missing", "voter", 1); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("RateExcuse missing: got %v, want ErrNotFound", err)
+	}
+	if _, err := store.RateExcuse(ctx, "e1", "voter", 5); !errors.Is(err, storage.ErrInvalid) {
+		t.Fatalf("RateExcuse invalid vote: got %v, want ErrInvalid", err)
+	}
+	if _, err := store.GetExcuses(ctx, storage.ExcuseFilter{}, storage.PageRequest{Sort: "bogus"}); !errors.Is(err, storage.ErrInvalid) {
+		t.Fatalf("GetExcuses bad sort: got %v, want ErrInvalid", err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := store.GetExcuse(canceled, "e1"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetExcuse with canceled context: got %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,91 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"procrastigo/internal/models"
+	"procrastigo/internal/storage"
+	"testing"
+)
+
+var dedupFixture = []models.Excuse{
+	{ID: "d1", Text: "Мой кот сел на клавиатуру и удалил весь код", Category: "tech", Language: "ru", Severity: "high", CreatedAt: Day(3)},
+	{ID: "d2", Text: "мой кот сел на клавиатуру, и удалил весь код!!!", Category: "tech", Language: "ru", Severity: "high", CreatedAt: Day(1)},
+	{ID: "d3", Text: "Мой кот сел на клавиатуру и удалил почти весь код", Category: "tech", Language: "ru", Severity: "high", CreatedAt: Day(2)},
+	{ID: "d4", Text: "The dog ate my laptop charger", Category: "tech", Language: "en", Severity: "low", CreatedAt: Day(1)},
+	{ID: "d5", Text: "The dog ate my laptop charger again", Category: "tech", Language: "en", Severity: "low", CreatedAt: Day(2)},
+	{ID: "d6", Text: "Интернет отключили во всем районе", Category: "tech", Language: "ru", Severity: "low", CreatedAt: Day(1)},
+}
+
+func testFindSimilar(t *testing.T, store storage.Storage) {
+	Seed(t, store, dedupFixture)
+	ctx := context.Background()
+
+	similar, err := store.FindSimilar(ctx, "МОЙ КОТ сел на клавиатуру... и удалил весь код", 0.8, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, 0, len(similar))
+	for _, s := range similar {
+		got = append(got, s.Excuse.ID)
+	}
+	if !equal(got, []string{"d1", "d2", "d3"}) {
+		t.Fatalf("got %v, want [d1 d2 d3]", got)
+	}
+	if similar[0].Similarity < 0.99 || similar[2].Similarity >= similar[0].Similarity {
+		t.Fatalf("unexpected similarities: %+v", similar)
+	}
+
+	if similar, err := store.FindSimilar(ctx, "Мой кот сел на клавиатуру", 0.8, 1); err != nil || len(similar) != 0 {
+		t.Fatalf("shorter text: got %+v, %v; want nothing above 0.8", similar, err)
+	}
+	if similar, err := store.FindSimilar(ctx, "мой кот сел на клавиатуру и удалил весь код", 0.5, 1); err != nil || len(similar) != 1 {
+		t.Fatalf("limit: got %+v, %v", similar, err)
+	}
+	if _, err := store.FindSimilar(ctx, "text", 1.5, 1); !errors.Is(err, storage.ErrInvalid) {
+		t.Fatalf("bad threshold: got %v, want ErrInvalid", err)
+	}
+
+	// неопубликованные оправдания не считаются дубликатами
+	Seed(t, store, []models.Excuse{
+		{ID: "d7", Text: "Соседи сверлили стену с самого утра", Category: "family", Language: "ru", Severity: "low", CreatedAt: Day(4), Status: models.StatusPending},
+		{ID: "d8", Text: "Соседи сверлили стену с самого утра!", Category: "family", Language: "ru", Severity: "low", CreatedAt: Day(4), Status: models.StatusRejected, RejectionReason: "spam"},
+	})
+	if similar, err := store.FindSimilar(ctx, "соседи сверлили стену с самого утра", 0.8, 0); err != nil || len(similar) != 0 {
+		t.Fatalf("unpublished: got %+v, %v; want nothing", similar, err)
+	}
+}
+
+func testDuplicateClusters(t *testing.T, store storage.Storage) {
+	Seed(t, store, dedupFixture)
+	ctx := context.Background()
+
+	clusters, err := store.DuplicateClusters(ctx, 0.7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clusters) != 2 {
+		t.Fatalf("got %d clusters, want 2: %+v", len(clusters), clusters)
+	}
+	// самая большая группа первой, внутри - по created_at
+	if got := ids(clusters[0].Excuses); !equal(got, []string{"d2", "d3", "d1"}) {
+		t.Errorf("first cluster %v, want [d2 d3 d1]", got)
+	}
+	if got := ids(clusters[1].Excuses); !equal(got, []string{"d4", "d5"}) {
+		t.Errorf("second cluster %v, want [d4 d5]", got)
+	}
+	if clusters[0].Similarity < 0.99 {
+		t.Errorf("first cluster similarity %g, want ~1", clusters[0].Similarity)
+	}
+
+	if err := store.DeleteExcuse(ctx, "d5"); err != nil {
+		t.Fatal(err)
+	}
+	clusters, err = store.DuplicateClusters(ctx, 0.7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clusters) != 1 {
+		t.Errorf("after delete: got %d clusters, want 1", len(clusters))
+	}
+}
@@ -0,0 +1,89 @@
+package storagetest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"procrastigo/internal/catalog"
+	"procrastigo/internal/storage"
+	"testing"
+)
+
+// testCatalog - справочники с кодами, которые используют файлы тестов
+func testCatalog(t *testing.T) *catalog.Catalog {
+	t.Helper()
+	entries := func(codes ...string) []catalog.Entry {
+		result := make([]catalog.Entry, len(codes))
+		for i, code := range codes {
+			result[i] = catalog.Entry{Code: code}
+		}
+		return result
+	}
+	cat, err := catalog.New(
+		catalog.Defaults{Category: "work", Language: "en", Severity: "low"},
+		entries("work", "tech"), entries("en", "ru"), entries("low", "high"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cat
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "excuses.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func testLoadFromFile(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	opts := storage.LoadOptions{Mode: storage.LoadStrict, Catalog: testCatalog(t)}
+	path := writeFile(t, `{"excuses": [
+  {"id": "a", "text": "one", "category": "work", "language": "en", "severity": "low", "created_at": "2024-01-01T00:00:00Z", "rating": 3},
+  {"id": "b", "text": "two", "category": "work", "language": "en", "severity": "low", "created_at": "2024-01-01T00:00:00Z"}
+]}`)
+
+	report, err := store.LoadFromFile(ctx, path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Inserted != 2 || report.Updated != 0 || report.Unchanged != 0 {
+		t.Fatalf("first load: %+v", report)
+	}
+
+	report, err = store.LoadFromFile(ctx, path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Inserted != 0 || report.Updated != 0 || report.Unchanged != 2 {
+		t.Fatalf("second load: %+v", report)
+	}
+
+	if _, err := store.RateExcuse(ctx, "a", "voter", 1); err != nil {
+		t.Fatal(err)
+	}
+	changed := writeFile(t, `{"excuses": [
+  {"id": "a", "text": "one, edited", "category": "tech", "language": "en", "severity": "low", "created_at": "2030-01-01T00:00:00Z", "rating": 100}
+]}`)
+	report, err = store.LoadFromFile(ctx, changed, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Updated != 1 {
+		t.Fatalf("changed load: %+v", report)
+	}
+
+	got, err := store.GetExcuse(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Text != "one, edited" || got.Category != "tech" {
+		t.Errorf("fields not updated: %+v", got)
+	}
+	if got.Rating != 1 || got.CreatedAt.Year() != 2024 {
+		t.Errorf("rating and created_at must be kept, got %+v", got)
+	}
+}
@@ -0,0 +1,152 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"procrastigo/internal/models"
+	"procrastigo/internal/storage"
+	"sort"
+	"testing"
+)
+
+func testModeration(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	excuse := func(id, status string) models.Excuse {
+		return models.Excuse{ID: id, Text: "moderated excuse " + id, Category: "work", Language: "en", Severity: "low",
+			CreatedAt: Day(1), Status: status, Tags: []string{"queue"}}
+	}
+	Seed(t, store, []models.Excuse{
+		excuse("m1", models.StatusApproved),
+		excuse("m2", models.StatusPending),
+		excuse("m3", models.StatusPending),
+		excuse("m4", ""), // без статуса - одобренное
+	})
+
+	got, err := store.GetExcuse(ctx, "m4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.StatusApproved {
+		t.Errorf("empty status stored as %q", got.Status)
+	}
+
+	approved := storage.ExcuseFilter{Status: models.StatusApproved}
+	visible := func() []string {
+		t.Helper()
+		page, err := store.GetExcuses(ctx, approved, storage.PageRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := ids(page.Excuses)
+		sort.Strings(result)
+		return result
+	}
+	if got := visible(); !equal(got, []string{"m1", "m4"}) {
+		t.Errorf("approved excuses: %v", got)
+	}
+	for i := 0; i < 20; i++ {
+		random, err := store.GetRandomExcuse(ctx, approved)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if random.Status != models.StatusApproved {
+			t.Fatalf("random returned %s excuse %s", random.Status, random.ID)
+		}
+	}
+	hits, err := store.SearchExcuses(ctx, storage.SearchQuery{Text: "moderated", Filter: approved})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 {
+		t.Errorf("search must skip pending excuses: %d hits", len(hits))
+	}
+	if tags, err := store.GetTags(ctx); err != nil || len(tags) != 1 || tags[0].Count != 2 {
+		t.Errorf("tags must count approved excuses only: %v, %v", tags, err)
+	}
+
+	// правка оправдания на модерации не публикует его
+	edited, err := store.UpdateExcuse(ctx, models.Excuse{ID: "m2", Text: "moderated excuse m2", Category: "work", Language: "en", Severity: "low", Tags: []string{"queue"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Status != models.StatusPending {
+		t.Errorf("update published a pending excuse: %+v", edited)
+	}
+	if got := visible(); !equal(got, []string{"m1", "m4"}) {
+		t.Errorf("approved excuses after editing a pending one: %v", got)
+	}
+
+	moderated, err := store.ModerateExcuse(ctx, "m2", models.StatusApproved, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moderated.Status != models.StatusApproved || moderated.Text != "moderated excuse m2" {
+		t.Errorf("approved excuse: %+v", moderated)
+	}
+	if got := visible(); !equal(got, []string{"m1", "m2", "m4"}) {
+		t.Errorf("approved excuses after approval: %v", got)
+	}
+
+	if _, err := store.ModerateExcuse(ctx, "m3", models.StatusRejected, "off-topic"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := store.GetExcuse(ctx, "m3"); got == nil || got.Status != models.StatusRejected || got.RejectionReason != "off-topic" {
+		t.Errorf("rejected excuse: %+v", got)
+	}
+	rejected, err := store.GetExcuses(ctx, storage.ExcuseFilter{Status: models.StatusRejected}, storage.PageRequest{})
+	if err != nil || !equal(ids(rejected.Excuses), []string{"m3"}) {
+		t.Errorf("rejected filter: %v, %v", rejected, err)
+	}
+
+	// правка не возвращает оправдание в выдачу
+	updated, err := store.UpdateExcuse(ctx, models.Excuse{ID: "m3", Text: "edited", Category: "work", Language: "en", Severity: "low"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status != models.StatusRejected || updated.RejectionReason != "off-topic" {
+		t.Errorf("update changed moderation status: %+v", updated)
+	}
+
+	errs := []struct {
+		name           string
+		id             string
+		status, reason string
+		want           error
+	}{
+		{"already approved", "m2", models.StatusRejected, "late", storage.ErrConflict},
+		{"already rejected", "m3", models.StatusApproved, "", storage.ErrConflict},
+		{"unknown id", "missing", models.StatusApproved, "", storage.ErrNotFound},
+		{"reject without reason", "m1", models.StatusRejected, " ", storage.ErrInvalid},
+		{"approve with reason", "m1", models.StatusApproved, "why", storage.ErrInvalid},
+		{"pending is not a decision", "m1", models.StatusPending, "", storage.ErrInvalid},
+	}
+	for _, tc := range errs {
+		if _, err := store.ModerateExcuse(ctx, tc.id, tc.status, tc.reason); !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
+
+func testLoadKeepsStatus(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	Seed(t, store, []models.Excuse{{ID: "a", Text: "one", Category: "work", Language: "en", Severity: "low",
+		CreatedAt: Day(1), Status: models.StatusPending}})
+
+	path := writeFile(t, `{"excuses": [
+  {"id": "a", "text": "one, edited", "category": "work", "language": "en", "severity": "low", "created_at": "2024-01-01T00:00:00Z"},
+  {"id": "b", "text": "two", "category": "work", "language": "en", "severity": "low", "created_at": "2024-01-01T00:00:00Z", "status": "pending"}
+]}`)
+	if _, err := store.LoadFromFile(ctx, path, storage.LoadOptions{Mode: storage.LoadStrict, Catalog: testCatalog(t)}); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, want := range map[string]string{"a": models.StatusPending, "b": models.StatusPending} {
+		got, err := store.GetExcuse(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != want {
+			t.Errorf("%s: status %q, want %q", id, got.Status, want)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"procrastigo/internal/models"
+	"procrastigo/internal/storage"
+	"sort"
+	"testing"
+)
+
+var filterFixture = []models.Excuse{
+	{ID: "f1", Text: "one", Category: "work", Language: "en", Severity: "low", CreatedAt: Day(1)},
+	{ID: "f2", Text: "two", Category: "work", Language: "ru", Severity: "high", CreatedAt: Day(2)},
+	{ID: "f3", Text: "three", Category: "tech", Language: "en", Severity: "critical", CreatedAt: Day(3)},
+	{ID: "f4", Text: "four", Category: "tech", Language: "ru", Severity: "medium", CreatedAt: Day(4)},
+	{ID: "f5", Text: "five", Category: "general", Language: "en", Severity: "high", CreatedAt: Day(5)},
+}
+
+func testFilters(t *testing.T, store storage.Storage) {
+	minRating := 1
+	fixture := append([]models.Excuse(nil), filterFixture...)
+	fixture[3].Rating = 2 // f4
+
+	cases := []struct {
+		name   string
+		filter storage.ExcuseFilter
+		want   []string
+	}{
+		{"empty", storage.ExcuseFilter{}, []string{"f1", "f2", "f3", "f4", "f5"}},
+		{"category", storage.ExcuseFilter{Category: "work"}, []string{"f1", "f2"}},
+		{"language", storage.ExcuseFilter{Language: "en"}, []string{"f1", "f3", "f5"}},
+		{"single severity", storage.ExcuseFilter{Severities: []string{"high"}}, []string{"f2", "f5"}},
+		{"multiple severities", storage.ExcuseFilter{Severities: []string{"high", "critical"}}, []string{"f2", "f3", "f5"}},
+		{"min rating", storage.ExcuseFilter{MinRating: &minRating}, []string{"f4"}},
+		{"created after is inclusive", storage.ExcuseFilter{CreatedAfter: Day(4)}, []string{"f4", "f5"}},
+		{"created before is exclusive", storage.ExcuseFilter{CreatedBefore: Day(2)}, []string{"f1"}},
+		{"created range", storage.ExcuseFilter{CreatedAfter: Day(2), CreatedBefore: Day(4)}, []string{"f2", "f3"}},
+		{"combined", storage.ExcuseFilter{Language: "en", Severities: []string{"low", "critical"}, CreatedAfter: Day(2)}, []string{"f3"}},
+		{"no match", storage.ExcuseFilter{Category: "health"}, nil},
+	}
+
+	Seed(t, store, fixture)
+	ctx := context.Background()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := store.GetExcuses(ctx, tc.filter, storage.PageRequest{})
+			if err != nil {
+				t.Fatalf("GetExcuses: %v", err)
+			}
+			got := ids(page.Excuses)
+			sort.Strings(got)
+			if !equal(got, tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// pageFixture содержит одинаковые рейтинги и даты, чтобы проверить,
+// что порядок строгий за счет id.
+var pageFixture = []models.Excuse{
+	{ID: "p1", Text: "1", Category: "work", Language: "en", Severity: "low", CreatedAt: Day(1), Rating: 5},
+	{ID: "p2", Text: "2", Category: "work", Language: "en", Severity: "low", CreatedAt: Day(2), Rating: 5},
+	{ID: "p3", Text: "3", Category: "work", Language: "en", Severity: "low", CreatedAt: Day(2), Rating: 1},
+	{ID: "p4", Text: "4", Category: "work", Language: "en", Severity: "low", CreatedAt: Day(2), Rating: 5},
+	{ID: "p5", Text: "5", Category: "work", Language: "en", Severity: "low", CreatedAt: Day(3), Rating: -2},
+	{ID: "p6", Text: "6", Category: "tech", Language: "en", Severity: "low", CreatedAt: Day(4), Rating: 9},
+}
+
+func testPagination(t *testing.T, store storage.Storage) {
+	cases := []struct {
+		sort   string
+		filter storage.ExcuseFilter
+		want   []string
+	}{
+		{storage.SortRating, storage.ExcuseFilter{}, []string{"p6", "p4", "p2", "p1", "p3", "p5"}},
+		{storage.SortCreatedAt, storage.ExcuseFilter{}, []string{"p1", "p2", "p3", "p4", "p5", "p6"}},
+		{storage.SortCreatedAtDesc, storage.ExcuseFilter{}, []string{"p6", "p5", "p4", "p3", "p2", "p1"}},
+		{storage.SortRating, storage.ExcuseFilter{Category: "work"}, []string{"p4", "p2", "p1", "p3", "p5"}},
+	}
+
+	Seed(t, store, pageFixture)
+	ctx := context.Background()
+
+	for _, tc := range cases {
+		for _, limit := range []int{1, 2, 4, 10} {
+			var got []string
+			req := storage.PageRequest{Sort: tc.sort, Limit: limit}
+			for pages := 0; ; pages++ {
+				if pages > len(pageFixture) {
+					t.Fatalf("sort=%s limit=%d: pagination does not terminate", tc.sort, limit)
+				}
+				page, err := store.GetExcuses(ctx, tc.filter, req)
+				if err != nil {
+					t.Fatalf("sort=%s limit=%d: %v", tc.sort, limit, err)
+				}
+				if len(page.Excuses) > limit {
+					t.Fatalf("sort=%s limit=%d: got %d excuses", tc.sort, limit, len(page.Excuses))
+				}
+				got = append(got, ids(page.Excuses)...)
+				if page.NextCursor == "" {
+					break
+				}
+				req.Cursor = page.NextCursor
+			}
+
+			if !equal(got, tc.want) {
+				t.Fatalf("sort=%s limit=%d: got %v, want %v", tc.sort, limit, got, tc.want)
+			}
+		}
+	}
+}
+
+// testIDByteOrder: при равных рейтинге и дате порядок задают id, сравненные
+// побайтно - пунктуация и регистр не игнорируются, как в collation базы.
+func testIDByteOrder(t *testing.T, store storage.Storage) {
+	fixture := []string{"a_b", "ab", "a-c", "aB", "a.d"}
+	for _, id := range fixture {
+		Seed(t, store, []models.Excuse{{ID: id, Text: "excuse " + id, Category: "work", Language: "en", Severity: "low", CreatedAt: Day(1)}})
+	}
+	asc := append([]string(nil), fixture...)
+	sort.Strings(asc)
+	desc := make([]string, len(asc))
+	for i, id := range asc {
+		desc[len(asc)-1-i] = id
+	}
+
+	ctx := context.Background()
+	for sortBy, want := range map[string][]string{storage.SortCreatedAt: asc, storage.SortRating: desc} {
+		var got []string
+		req := storage.PageRequest{Sort: sortBy, Limit: 2}
+		for pages := 0; pages <= len(fixture); pages++ {
+			page, err := store.GetExcuses(ctx, storage.ExcuseFilter{}, req)
+			if err != nil {
+				t.Fatalf("sort=%s: %v", sortBy, err)
+			}
+			got = append(got, ids(page.Excuses)...)
+			if page.NextCursor == "" {
+				break
+			}
+			req.Cursor = page.NextCursor
+		}
+		if !equal(got, want) {
+			t.Errorf("sort=%s: got %v, want %v", sortBy, got, want)
+		}
+	}
+}
+
+func testForeignCursor(t *testing.T, store storage.Storage) {
+	Seed(t, store, pageFixture)
+	ctx := context.Background()
+
+	page, err := store.GetExcuses(ctx, storage.ExcuseFilter{}, storage.PageRequest{Sort: storage.SortRating, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.GetExcuses(ctx, storage.ExcuseFilter{}, storage.PageRequest{Sort: storage.SortCreatedAt, Limit: 2, Cursor: page.NextCursor})
+	if !errors.Is(err, storage.ErrInvalidCursor) {
+		t.Fatalf("got %v, want ErrInvalidCursor", err)
+	}
+
+	_, err = store.GetExcuses(ctx, storage.ExcuseFilter{}, storage.PageRequest{Limit: 2, Cursor: "garbage!"})
+	if !errors.Is(err, storage.ErrInvalidCursor) {
+		t.Fatalf("got %v, want ErrInvalidCursor", err)
+	}
+}
+
+func testRandom(t *testing.T, store storage.Storage) {
+	minRating := 3
+	fixture := append([]models.Excuse(nil), filterFixture...)
+	fixture[2].Rating = 5 // f3
+
+	Seed(t, store, fixture)
+	ctx := context.Background()
+
+	cases := []struct {
+		name    string
+		filter  storage.ExcuseFilter
+		matches int
+	}{
+		{"language", storage.ExcuseFilter{Language: "ru"}, 2},
+		{"category and language", storage.ExcuseFilter{Category: "tech", Language: "en"}, 1},
+		{"severity", storage.ExcuseFilter{Severities: []string{"high", "critical"}}, 3},
+		{"min rating", storage.ExcuseFilter{MinRating: &minRating}, 1},
+	}
+	for _, tc := range cases {
+		seen := make(map[string]bool)
+		for i := 0; i < 50; i++ {
+			excuse, err := store.GetRandomExcuse(ctx, tc.filter)
+			if err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+			if !tc.filter.Matches(*excuse) {
+				t.Fatalf("%s: got non-matching excuse %+v", tc.name, excuse)
+			}
+			seen[excuse.ID] = true
+		}
+		if tc.matches > 1 && len(seen) < 2 {
+			t.Fatalf("%s: 50 draws returned only %v", tc.name, seen)
+		}
+	}
+
+	_, err := store.GetRandomExcuse(ctx, storage.ExcuseFilter{Category: "tech", Language: "en", Severities: []string{"low"}})
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("no match: got %v, want ErrNotFound", err)
+	}
+}
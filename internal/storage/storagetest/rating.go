@@ -0,0 +1,142 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"procrastigo/internal/models"
+	"procrastigo/internal/storage"
+	"sync"
+	"testing"
+)
+
+func testRating(t *testing.T, store storage.Storage) {
+	Seed(t, store, []models.Excuse{
+		{ID: "r1", Text: "vote", Category: "work", Language: "en", Severity: "low", CreatedAt: Day(1)},
+	})
+	ctx := context.Background()
+
+	steps := []struct {
+		voter string
+		vote  int
+		want  int
+	}{
+		{"alice", 1, 1},
+		{"alice", 1, 1}, // повторный голос не накручивает рейтинг
+		{"bob", 1, 2},
+		{"alice", -1, 0}, // голос можно изменить
+		{"carol", -1, -1},
+		{"bob", 0, -2}, // и отозвать
+		{"bob", 0, -2},
+	}
+	for i, step := range steps {
+		rating, err := store.RateExcuse(ctx, "r1", step.voter, step.vote)
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if rating != step.want {
+			t.Fatalf("step %d: rating %d, want %d", i, rating, step.want)
+		}
+	}
+
+	got, err := store.GetExcuse(ctx, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Rating != -2 {
+		t.Fatalf("stored rating %d, want -2", got.Rating)
+	}
+
+	votes, err := store.GetVotes(ctx, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(votes) != 2 || votes["alice"] != -1 || votes["carol"] != -1 {
+		t.Fatalf("GetVotes: %v, want alice and carol at -1", votes)
+	}
+	if _, err := store.GetVotes(ctx, "missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetVotes missing: got %v, want ErrNotFound", err)
+	}
+}
+
+// concurrency - число одновременных клиентов в тестах конкурентного доступа
+const concurrency = 20
+
+// testConcurrentVotes проверяет, что одновременные голоса не теряются
+func testConcurrentVotes(t *testing.T, store storage.Storage) {
+	Seed(t, store, []models.Excuse{
+		{ID: "v1", Text: "popular", Category: "work", Language: "en", Severity: "low", CreatedAt: Day(1)},
+	})
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(voter string) {
+			defer wg.Done()
+			// голос, его смена и возврат: каждый голосующий в итоге дает +1
+			for _, vote := range []int{1, -1, 1} {
+				if _, err := store.RateExcuse(ctx, "v1", voter, vote); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(fmt.Sprintf("voter-%d", i))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("RateExcuse: %v", err)
+	}
+
+	got, err := store.GetExcuse(ctx, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Rating != concurrency {
+		t.Fatalf("rating %d after %d concurrent voters, want %d", got.Rating, concurrency, concurrency)
+	}
+}
+
+// testConcurrentCreate проверяет, что из одновременных вставок одного id
+// успешна ровно одна, а остальные получают ErrConflict
+func testConcurrentCreate(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	results := make(chan error, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results <- store.CreateExcuse(ctx, models.Excuse{
+				ID: "same", Text: fmt.Sprintf("attempt %d", i),
+				Category: "work", Language: "en", Severity: "low", CreatedAt: Day(1),
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+
+	created := 0
+	for err := range results {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, storage.ErrConflict):
+			t.Errorf("CreateExcuse: got %v, want nil or ErrConflict", err)
+		}
+	}
+	if created != 1 {
+		t.Fatalf("%d concurrent creates succeeded, want 1", created)
+	}
+
+	stats, err := store.GetStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalExcuses != 1 {
+		t.Fatalf("total excuses %d, want 1", stats.TotalExcuses)
+	}
+}
@@ -0,0 +1,94 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"procrastigo/internal/models"
+	"procrastigo/internal/storage"
+	"strings"
+	"testing"
+)
+
+var searchFixture = []models.Excuse{
+	{ID: "s1", Text: "Мой кот сел на клавиатуру и удалил весь код", Category: "tech", Language: "ru", Severity: "high", CreatedAt: Day(1)},
+	{ID: "s2", Text: "Коты соседа опять орали всю ночь, кот за котом", Category: "social", Language: "ru", Severity: "low", CreatedAt: Day(2)},
+	{ID: "s3", Text: "My cat deleted the repository", Category: "tech", Language: "en", Severity: "high", CreatedAt: Day(3)},
+	{ID: "s4", Text: "The dog ate my laptop charger", Category: "tech", Language: "en", Severity: "medium", CreatedAt: Day(4)},
+}
+
+func testSearch(t *testing.T, store storage.Storage) {
+	Seed(t, store, searchFixture)
+	ctx := context.Background()
+
+	cases := []struct {
+		name  string
+		query storage.SearchQuery
+		want  []string // по убыванию релевантности
+	}{
+		{"russian word forms", storage.SearchQuery{Text: "кота"}, []string{"s2", "s1"}},
+		{"english word forms", storage.SearchQuery{Text: "cats"}, []string{"s3"}},
+		{"all words required", storage.SearchQuery{Text: "кот клавиатура"}, []string{"s1"}},
+		{"filter", storage.SearchQuery{Text: "кот", Filter: storage.ExcuseFilter{Category: "tech"}}, []string{"s1"}},
+		{"limit", storage.SearchQuery{Text: "кот", Limit: 1}, []string{"s2"}},
+		{"case insensitive", storage.SearchQuery{Text: "DELETED"}, []string{"s3"}},
+		{"no match", storage.SearchQuery{Text: "parrot"}, []string{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			hits, err := store.SearchExcuses(ctx, tc.query)
+			if err != nil {
+				t.Fatalf("SearchExcuses: %v", err)
+			}
+			got := make([]string, 0, len(hits))
+			for _, hit := range hits {
+				got = append(got, hit.Excuse.ID)
+			}
+			if !equal(got, tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	hits, err := store.SearchExcuses(ctx, storage.SearchQuery{Text: "кот"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hit := range hits {
+		if hit.Score <= 0 {
+			t.Errorf("%s: score %g, want positive", hit.Excuse.ID, hit.Score)
+		}
+		if !strings.Contains(hit.Highlight, "<mark>кот</mark>") || strings.ReplaceAll(strings.ReplaceAll(hit.Highlight, "<mark>", ""), "</mark>", "") != hit.Excuse.Text {
+			t.Errorf("%s: bad highlight %q", hit.Excuse.ID, hit.Highlight)
+		}
+	}
+
+	// индекс следует за изменениями текста
+	if _, err := store.UpdateExcuse(ctx, models.Excuse{ID: "s4", Text: "The parrot ate my charger", Category: "tech", Language: "en", Severity: "medium"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteExcuse(ctx, "s3"); err != nil {
+		t.Fatal(err)
+	}
+	if hits, err := store.SearchExcuses(ctx, storage.SearchQuery{Text: "parrot"}); err != nil || len(hits) != 1 {
+		t.Errorf("after update: got %v, %v", hits, err)
+	}
+	if hits, err := store.SearchExcuses(ctx, storage.SearchQuery{Text: "cat"}); err != nil || len(hits) != 0 {
+		t.Errorf("after delete: got %v, %v", hits, err)
+	}
+
+	// подсветка экранирует текст для HTML
+	Seed(t, store, []models.Excuse{
+		{ID: "s5", Text: "The <b>parrot</b> & the cat", Category: "tech", Language: "en", Severity: "low", CreatedAt: Day(5)},
+	})
+	hits, err = store.SearchExcuses(ctx, storage.SearchQuery{Text: "cat"})
+	if err != nil || len(hits) != 1 {
+		t.Fatalf("escaping: got %v, %v", hits, err)
+	}
+	if want := "The &lt;b&gt;parrot&lt;/b&gt; &amp; the <mark>cat</mark>"; hits[0].Highlight != want {
+		t.Errorf("escaping: highlight %q, want %q", hits[0].Highlight, want)
+	}
+
+	if _, err := store.SearchExcuses(ctx, storage.SearchQuery{Text: " the, и "}); !errors.Is(err, storage.ErrInvalid) {
+		t.Errorf("stop words only: got %v, want ErrInvalid", err)
+	}
+}
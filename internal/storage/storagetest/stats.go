@@ -0,0 +1,53 @@
+package storagetest
+
+import (
+	"context"
+	"procrastigo/internal/models"
+	"procrastigo/internal/storage"
+	"procrastigo/pkg/utils"
+	"testing"
+	"time"
+)
+
+func testStats(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+
+	stats, err := store.GetStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalExcuses != 0 || stats.ExcusesToday != 0 || stats.MostPopularCategory != "" {
+		t.Fatalf("stats of empty storage: %+v", stats)
+	}
+
+	// "сегодня" - текущие сутки по UTC, без вчерашних и завтрашних
+	today := utils.GetStartOfDay()
+	Seed(t, store, []models.Excuse{
+		{ID: "s1", Text: "1", Category: "work", Language: "en", Severity: "low", CreatedAt: today},
+		{ID: "s2", Text: "2", Category: "work", Language: "en", Severity: "low", CreatedAt: today.Add(time.Minute)},
+		{ID: "s3", Text: "3", Category: "tech", Language: "en", Severity: "low", CreatedAt: today.Add(-time.Nanosecond)},
+		{ID: "s4", Text: "4", Category: "tech", Language: "en", Severity: "low", CreatedAt: today.Add(24 * time.Hour)},
+		{ID: "s5", Text: "5", Category: "general", Language: "en", Severity: "low", CreatedAt: Day(1)},
+		// неопубликованные не считаются, иначе general победила бы
+		{ID: "s6", Text: "6", Category: "general", Language: "en", Severity: "low", CreatedAt: today, Status: models.StatusPending},
+		{ID: "s7", Text: "7", Category: "general", Language: "en", Severity: "low", CreatedAt: today, Status: models.StatusRejected, RejectionReason: "spam"},
+	})
+
+	stats, err = store.GetStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalExcuses != 5 {
+		t.Errorf("total excuses %d, want 5", stats.TotalExcuses)
+	}
+	if stats.ExcusesToday != 2 {
+		t.Errorf("excuses today %d, want 2", stats.ExcusesToday)
+	}
+	// при равенстве побеждает категория, первая по алфавиту
+	if stats.MostPopularCategory != "tech" {
+		t.Errorf("most popular category %q, want tech", stats.MostPopularCategory)
+	}
+	if want := utils.CalculateProcrastinationLevel(2); stats.GlobalProcrastinationLevel != want {
+		t.Errorf("procrastination level %q, want %q", stats.GlobalProcrastinationLevel, want)
+	}
+}
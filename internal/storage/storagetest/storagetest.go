@@ -0,0 +1,98 @@
+// Package storagetest - общий набор тестов, который должна проходить
+// любая реализация storage.Storage. Бэкенд подключается одной функцией,
+// создающей пустое хранилище:
+//
+//	func TestConformance(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storage.Storage {
+//			return storage.NewMemoryStorage()
+//		})
+//	}
+package storagetest
+
+import (
+	"context"
+	"procrastigo/internal/models"
+	"procrastigo/internal/storage"
+	"testing"
+	"time"
+)
+
+// Factory создает пустое хранилище для одного теста. Run закрывает его
+// по окончании теста.
+type Factory func(t *testing.T) storage.Storage
+
+var cases = []struct {
+	name string
+	test func(t *testing.T, store storage.Storage)
+}{
+	{"CRUD", testCRUD},
+	{"ErrorCategories", testErrorCategories},
+	{"Filters", testFilters},
+	{"Pagination", testPagination},
+	{"IDByteOrder", testIDByteOrder},
+	{"ForeignCursor", testForeignCursor},
+	{"Random", testRandom},
+	{"Rating", testRating},
+	{"Stats", testStats},
+	{"LoadFromFile", testLoadFromFile},
+	{"Tags", testTags},
+	{"LoadTags", testLoadTags},
+	{"Moderation", testModeration},
+	{"LoadKeepsStatus", testLoadKeepsStatus},
+	{"Search", testSearch},
+	{"FindSimilar", testFindSimilar},
+	{"DuplicateClusters", testDuplicateClusters},
+	{"ConcurrentVotes", testConcurrentVotes},
+	{"ConcurrentCreate", testConcurrentCreate},
+}
+
+// Run прогоняет все тесты набора, каждый на новом хранилище из newStorage.
+func Run(t *testing.T, newStorage Factory) {
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newStorage(t)
+			defer func() {
+				if err := store.Close(); err != nil {
+					t.Errorf("Close: %v", err)
+				}
+			}()
+			tc.test(t, store)
+		})
+	}
+}
+
+// Seed создает оправдания через CreateExcuse и останавливает тест при ошибке.
+func Seed(t *testing.T, store storage.Storage, excuses []models.Excuse) {
+	t.Helper()
+	for _, excuse := range excuses {
+		if err := store.CreateExcuse(context.Background(), excuse); err != nil {
+			t.Fatalf("CreateExcuse(%s): %v", excuse.ID, err)
+		}
+	}
+}
+
+// Day возвращает полдень d января 2024 года (UTC)
+func Day(d int) time.Time {
+	return time.Date(2024, time.January, d, 12, 0, 0, 0, time.UTC)
+}
+
+// ids возвращает id оправданий в том же порядке
+func ids(excuses []models.Excuse) []string {
+	var result []string
+	for _, excuse := range excuses {
+		result = append(result, excuse.ID)
+	}
+	return result
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,129 @@
+package storagetest
+
+import (
+	"context"
+	"procrastigo/internal/models"
+	"procrastigo/internal/storage"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func testTags(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	tagged := func(id string, tags ...string) models.Excuse {
+		return models.Excuse{ID: id, Text: "excuse " + id, Category: "work", Language: "en", Severity: "low", CreatedAt: Day(1), Tags: tags}
+	}
+	Seed(t, store, []models.Excuse{
+		tagged("t1", "deploy", "meetings"),
+		tagged("t2", "meetings"),
+		tagged("t3", "code-review", "deploy", "meetings"),
+		tagged("t4"),
+	})
+
+	got, err := store.GetExcuse(ctx, "t3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(got.Tags, []string{"code-review", "deploy", "meetings"}) {
+		t.Errorf("tags of t3: %v", got.Tags)
+	}
+
+	filters := []struct {
+		name   string
+		filter storage.ExcuseFilter
+		want   []string
+	}{
+		{"any", storage.ExcuseFilter{Tags: []string{"deploy", "code-review"}, TagMode: storage.TagModeAny}, []string{"t1", "t3"}},
+		{"any by default", storage.ExcuseFilter{Tags: []string{"meetings"}}, []string{"t1", "t2", "t3"}},
+		{"all", storage.ExcuseFilter{Tags: []string{"deploy", "meetings"}, TagMode: storage.TagModeAll}, []string{"t1", "t3"}},
+		{"all with missing tag", storage.ExcuseFilter{Tags: []string{"deploy", "unknown"}, TagMode: storage.TagModeAll}, nil},
+		{"with other fields", storage.ExcuseFilter{Tags: []string{"meetings"}, ExcludeIDs: []string{"t1"}}, []string{"t2", "t3"}},
+	}
+	for _, tc := range filters {
+		page, err := store.GetExcuses(ctx, tc.filter, storage.PageRequest{})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		got := ids(page.Excuses)
+		sort.Strings(got)
+		if !equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	random, err := store.GetRandomExcuse(ctx, storage.ExcuseFilter{Tags: []string{"code-review"}})
+	if err != nil || random.ID != "t3" {
+		t.Errorf("random by tag: %v, %v", random, err)
+	}
+
+	// обновление заменяет теги целиком
+	updated, err := store.UpdateExcuse(ctx, models.Excuse{ID: "t1", Text: "excuse t1", Category: "work", Language: "en", Severity: "low", Tags: []string{"standup"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(updated.Tags, []string{"standup"}) {
+		t.Errorf("updated tags: %v", updated.Tags)
+	}
+	if err := store.DeleteExcuse(ctx, "t2"); err != nil {
+		t.Fatal(err)
+	}
+
+	counts, err := store.GetTags(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.TagCount{{Tag: "code-review", Count: 1}, {Tag: "deploy", Count: 1}, {Tag: "meetings", Count: 1}, {Tag: "standup", Count: 1}}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("GetTags: got %v, want %v", counts, want)
+	}
+
+	Seed(t, store, []models.Excuse{tagged("t5", "deploy")})
+	counts, err = store.GetTags(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// самый частый тег первым, остальные по алфавиту
+	want = []models.TagCount{{Tag: "deploy", Count: 2}, {Tag: "code-review", Count: 1}, {Tag: "meetings", Count: 1}, {Tag: "standup", Count: 1}}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("GetTags: got %v, want %v", counts, want)
+	}
+}
+
+func testLoadTags(t *testing.T, store storage.Storage) {
+	ctx := context.Background()
+	opts := storage.LoadOptions{Mode: storage.LoadStrict, Catalog: testCatalog(t)}
+	load := func(tags string) *storage.LoadReport {
+		t.Helper()
+		path := writeFile(t, `{"excuses": [
+  {"id": "a", "text": "one", "category": "work", "language": "en", "severity": "low", "created_at": "2024-01-01T00:00:00Z", "tags": `+tags+`}
+]}`)
+		report, err := store.LoadFromFile(ctx, path, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return report
+	}
+
+	if report := load(`["Deploy", "meetings", "deploy"]`); report.Inserted != 1 {
+		t.Fatalf("first load: %+v", report)
+	}
+	got, err := store.GetExcuse(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(got.Tags, []string{"deploy", "meetings"}) {
+		t.Errorf("loaded tags must be normalized: %v", got.Tags)
+	}
+
+	if report := load(`["meetings", "deploy"]`); report.Unchanged != 1 {
+		t.Errorf("same tags in another order: %+v", report)
+	}
+	// изменились только теги - запись считается обновленной
+	if report := load(`["deploy"]`); report.Updated != 1 {
+		t.Errorf("tag-only change: %+v", report)
+	}
+	if got, _ := store.GetExcuse(ctx, "a"); got == nil || !equal(got.Tags, []string{"deploy"}) {
+		t.Errorf("tags after reload: %v", got)
+	}
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"fmt"
+	"procrastigo/internal/models"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Ограничения тегов
+const (
+	MaxTags      = 10
+	MaxTagLength = 32
+)
+
+// Режимы фильтра по тегам
+const (
+	TagModeAny = "any" // есть хотя бы один из тегов
+	TagModeAll = "all" // есть все теги
+)
+
+// ValidTagMode сообщает, поддерживается ли режим фильтра по тегам.
+func ValidTagMode(mode string) bool {
+	return mode == TagModeAny || mode == TagModeAll
+}
+
+// NormalizeTags приводит теги к нижнему регистру, убирает пробелы по краям
+// и повторы и сортирует их. Тег - от 1 до MaxTagLength букв, цифр, "-"
+// и "_"; тегов не больше MaxTags. Пустой список дает nil.
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	var result []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			return nil, fmt.Errorf("tag is empty")
+		}
+		if utf8.RuneCountInString(tag) > MaxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, MaxTagLength)
+		}
+		for _, r := range tag {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+				return nil, fmt.Errorf("tag %q may contain only letters, digits, '-' and '_'", tag)
+			}
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			result = append(result, tag)
+		}
+	}
+	if len(result) > MaxTags {
+		return nil, fmt.Errorf("too many tags: %d, at most %d", len(result), MaxTags)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// matchTags проверяет теги оправдания по фильтру в режиме mode
+func matchTags(tags, wanted []string, mode string) bool {
+	for _, tag := range wanted {
+		found := containsString(tags, tag)
+		if found && mode != TagModeAll {
+			return true
+		}
+		if !found && mode == TagModeAll {
+			return false
+		}
+	}
+	return mode == TagModeAll
+}
+
+// equalTags сравнивает отсортированные списки тегов
+func equalTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sortTagCounts упорядочивает теги по убыванию числа оправданий, при
+// равенстве - по алфавиту. Порядок не зависит от сортировки в базе.
+func sortTagCounts(counts []models.TagCount) {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Tag < counts[j].Tag
+	})
+}
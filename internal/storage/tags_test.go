@@ -0,0 +1,33 @@
+package storage
+
+import "testing"
+
+func TestNormalizeTags(t *testing.T) {
+	got, err := NormalizeTags([]string{" Deploy", "code_review", "deploy", "Митинги", "ci-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalTags(got, []string{"ci-2", "code_review", "deploy", "митинги"}) {
+		t.Errorf("got %v", got)
+	}
+
+	if got, err := NormalizeTags(nil); err != nil || got != nil {
+		t.Errorf("no tags: %v, %v", got, err)
+	}
+
+	tooMany := make([]string, MaxTags+1)
+	for i := range tooMany {
+		tooMany[i] = string(rune('a' + i))
+	}
+	for _, tags := range [][]string{
+		{""},
+		{"a,b"},
+		{"two words"},
+		{"0123456789012345678901234567890123"},
+		tooMany,
+	} {
+		if _, err := NormalizeTags(tags); err == nil {
+			t.Errorf("%q accepted", tags)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+// Package transfer переносит оправдания вместе с голосами между хранилищем
+// и файлами в форматах JSON (ExcuseFileFormat), CSV и NDJSON.
+package transfer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"procrastigo/internal/models"
+	"procrastigo/internal/storage"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Поддерживаемые форматы
+const (
+	FormatJSON   = "json"
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// csvColumns - колонки CSV в порядке экспорта. Теги записываются в одну
+// колонку через ";", голоса - в колонку votes как "голосующий=голос" через ";".
+var csvColumns = []string{"id", "text", "category", "language", "severity", "created_at", "rating", "tags", "status", "rejection_reason", "votes"}
+
+// ValidFormat сообщает, поддерживается ли формат.
+func ValidFormat(format string) bool {
+	switch format {
+	case FormatJSON, FormatCSV, FormatNDJSON:
+		return true
+	}
+	return false
+}
+
+// FormatFromPath определяет формат по расширению файла (.json, .csv,
+// .ndjson или .jsonl). Для неизвестного расширения возвращает FormatJSON.
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON
+	}
+	return FormatJSON
+}
+
+// Record - запись входного файла. Err заполнен, если запись не удалось
+// разобрать; Index - ее позиция среди записей файла, с нуля.
+type Record struct {
+	Index  int
+	Excuse models.Excuse
+	Votes  map[string]int // голосующий -> голос; может быть пустым
+	Err    error
+}
+
+// ndjsonRecord - строка NDJSON: оправдание и его голоса
+type ndjsonRecord struct {
+	models.Excuse
+	Votes map[string]int `json:"votes,omitempty"`
+}
+
+// Decode читает все записи из r. Ошибка возвращается, только если файл
+// нельзя разобрать целиком (например, нет заголовка CSV); ошибки отдельных
+// записей попадают в Record.Err.
+func Decode(r io.Reader, format string) ([]Record, error) {
+	switch format {
+	case FormatJSON:
+		return decodeJSON(r)
+	case FormatCSV:
+		return decodeCSV(r)
+	case FormatNDJSON:
+		return decodeNDJSON(r)
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+func decodeJSON(r io.Reader) ([]Record, error) {
+	var raw struct {
+		Excuses []json.RawMessage         `json:"excuses"`
+		Votes   map[string]map[string]int `json:"votes"`
+	}
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	records := make([]Record, len(raw.Excuses))
+	for i, msg := range raw.Excuses {
+		records[i].Index = i
+		records[i].Err = json.Unmarshal(msg, &records[i].Excuse)
+		records[i].Votes = raw.Votes[records[i].Excuse.ID]
+	}
+	return records, nil
+}
+
+func decodeNDJSON(r io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var entry ndjsonRecord
+		rec := Record{Index: len(records)}
+		if err := json.Unmarshal([]byte(text), &entry); err != nil {
+			rec.Err = fmt.Errorf("line %d: %w", line, err)
+		}
+		rec.Excuse, rec.Votes = entry.Excuse, entry.Votes
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read NDJSON: %w", err)
+	}
+	return records, nil
+}
+
+func decodeCSV(r io.Reader) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !containsColumn(name) {
+			return nil, fmt.Errorf("unknown CSV column %q (expected %s)", name, strings.Join(csvColumns, ","))
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"id", "text"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header has no %q column", required)
+		}
+	}
+
+	var records []Record
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); !ok {
+				return nil, fmt.Errorf("failed to read CSV: %w", err)
+			}
+			records = append(records, Record{Index: len(records), Err: err})
+			continue
+		}
+		rec := Record{Index: len(records)}
+		rec.Excuse, rec.Err = csvExcuse(row, columns)
+		if rec.Err == nil {
+			rec.Votes, rec.Err = csvVotes(row, columns)
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+func csvExcuse(row []string, columns map[string]int) (models.Excuse, error) {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return row[i]
+	}
+
+	excuse := models.Excuse{
+		ID:              field("id"),
+		Text:            field("text"),
+		Category:        field("category"),
+		Language:        field("language"),
+		Severity:        field("severity"),
+		Status:          field("status"),
+		RejectionReason: field("rejection_reason"),
+	}
+	if value := field("created_at"); value != "" {
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return excuse, fmt.Errorf("invalid created_at %q: expected RFC3339", value)
+		}
+		excuse.CreatedAt = t
+	}
+	if value := field("rating"); value != "" {
+		rating, err := strconv.Atoi(value)
+		if err != nil {
+			return excuse, fmt.Errorf("invalid rating %q", value)
+		}
+		excuse.Rating = rating
+	}
+	if value := field("tags"); value != "" {
+		excuse.Tags = strings.Split(value, ";")
+	}
+	return excuse, nil
+}
+
+// csvVotes разбирает колонку votes
+func csvVotes(row []string, columns map[string]int) (map[string]int, error) {
+	i, ok := columns["votes"]
+	if !ok || i >= len(row) || row[i] == "" {
+		return nil, nil
+	}
+	votes := make(map[string]int)
+	for _, pair := range strings.Split(row[i], ";") {
+		voter, value, ok := strings.Cut(pair, "=")
+		vote, err := strconv.Atoi(value)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("invalid vote %q: expected voter=vote", pair)
+		}
+		votes[voter] = vote
+	}
+	return votes, nil
+}
+
+func containsColumn(name string) bool {
+	for _, c := range csvColumns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// encoder пишет оправдания с голосами по одному в выбранном формате
+type encoder interface {
+	encode(excuse models.Excuse, votes map[string]int) error
+	close() error
+}
+
+func newEncoder(w io.Writer, format string) (encoder, error) {
+	switch format {
+	case FormatJSON:
+		return &jsonEncoder{w: w}, nil
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvColumns); err != nil {
+			return nil, err
+		}
+		return &csvEncoder{w: cw}, nil
+	case FormatNDJSON:
+		return &ndjsonEncoder{enc: json.NewEncoder(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// jsonEncoder накапливает записи, так как ExcuseFileFormat - один объект
+type jsonEncoder struct {
+	w    io.Writer
+	file storage.ExcuseFileFormat
+}
+
+func (e *jsonEncoder) encode(excuse models.Excuse, votes map[string]int) error {
+	e.file.Excuses = append(e.file.Excuses, excuse)
+	if len(votes) > 0 {
+		if e.file.Votes == nil {
+			e.file.Votes = make(map[string]map[string]int)
+		}
+		e.file.Votes[excuse.ID] = votes
+	}
+	return nil
+}
+
+func (e *jsonEncoder) close() error {
+	if e.file.Excuses == nil {
+		e.file.Excuses = []models.Excuse{}
+	}
+	enc := json.NewEncoder(e.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(e.file)
+}
+
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvEncoder) encode(excuse models.Excuse, votes map[string]int) error {
+	pairs := make([]string, 0, len(votes))
+	for voter, vote := range votes {
+		pairs = append(pairs, voter+"="+strconv.Itoa(vote))
+	}
+	sort.Strings(pairs)
+
+	return e.w.Write([]string{
+		excuse.ID,
+		excuse.Text,
+		excuse.Category,
+		excuse.Language,
+		excuse.Severity,
+		excuse.CreatedAt.Format(time.RFC3339Nano),
+		strconv.Itoa(excuse.Rating),
+		strings.Join(excuse.Tags, ";"),
+		excuse.Status,
+		excuse.RejectionReason,
+		strings.Join(pairs, ";"),
+	})
+}
+
+func (e *csvEncoder) close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonEncoder) encode(excuse models.Excuse, votes map[string]int) error {
+	return e.enc.Encode(ndjsonRecord{Excuse: excuse, Votes: votes})
+}
+
+func (e *ndjsonEncoder) close() error {
+	return nil
+}
@@ -0,0 +1,239 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"procrastigo/internal/catalog"
+	"procrastigo/internal/models"
+	"procrastigo/internal/storage"
+	"sort"
+	"strings"
+)
+
+// exportPageSize - сколько записей читается из хранилища за раз
+const exportPageSize = 200
+
+// Export пишет все оправдания хранилища вместе с голосами в w в порядке
+// created_at и возвращает их количество. Голоса нужны, чтобы после импорта
+// следующий голос не пересчитал рейтинг с нуля.
+func Export(ctx context.Context, store storage.Storage, w io.Writer, format string) (int, error) {
+	enc, err := newEncoder(w, format)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	page := storage.PageRequest{Sort: storage.SortCreatedAt, Limit: exportPageSize}
+	for {
+		result, err := store.GetExcuses(ctx, storage.ExcuseFilter{}, page)
+		if err != nil {
+			return count, fmt.Errorf("failed to read excuses: %w", err)
+		}
+		for _, excuse := range result.Excuses {
+			votes, err := store.GetVotes(ctx, excuse.ID)
+			if err != nil {
+				return count, fmt.Errorf("failed to read votes for %s: %w", excuse.ID, err)
+			}
+			if err := enc.encode(excuse, votes); err != nil {
+				return count, fmt.Errorf("failed to write excuse %s: %w", excuse.ID, err)
+			}
+			count++
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		page.Cursor = result.NextCursor
+	}
+
+	if err := enc.close(); err != nil {
+		return count, fmt.Errorf("failed to write output: %w", err)
+	}
+	return count, nil
+}
+
+// Политики для записей, id которых уже есть в хранилище
+const (
+	// ConflictUpsert - обновить text, category, language и severity
+	// существующей записи и записать голоса из файла поверх ее голосов;
+	// created_at не меняется
+	ConflictUpsert = "upsert"
+	// ConflictSkip - оставить существующую запись
+	ConflictSkip = "skip"
+	// ConflictFail - отменить импорт целиком
+	ConflictFail = "fail"
+)
+
+// ValidConflictPolicy сообщает, известна ли политика.
+func ValidConflictPolicy(policy string) bool {
+	switch policy {
+	case ConflictUpsert, ConflictSkip, ConflictFail:
+		return true
+	}
+	return false
+}
+
+// ImportOptions - параметры Import
+type ImportOptions struct {
+	Format     string
+	OnConflict string           // ConflictUpsert, ConflictSkip или ConflictFail
+	Mode       string           // storage.LoadStrict или storage.LoadLenient для некорректных записей
+	Catalog    *catalog.Catalog // справочники для проверки записей
+	DryRun     bool             // только посчитать, ничего не записывая
+}
+
+// ImportReport - итог импорта. При DryRun счетчики показывают, что
+// было бы сделано.
+type ImportReport struct {
+	DryRun   bool
+	Total    int
+	Created  int
+	Updated  int
+	Skipped  int // конфликты при ConflictSkip
+	Invalid  int
+	Votes    int // голоса созданных и обновленных записей
+	Errors   []storage.RecordError
+	Conflict []string // id, уже существующие в хранилище
+}
+
+func (r *ImportReport) String() string {
+	prefix := ""
+	if r.DryRun {
+		prefix = "dry run: "
+	}
+	return fmt.Sprintf("%s%d record(s): %d created, %d updated, %d skipped, %d invalid; %d vote(s)",
+		prefix, r.Total, r.Created, r.Updated, r.Skipped, r.Invalid, r.Votes)
+}
+
+// ErrConflicts возвращается при ConflictFail, если часть id уже существует
+var ErrConflicts = errors.New("ids already exist")
+
+// Import загружает записи из r в хранилище. Сначала все записи разбираются
+// и проверяются (см. storage.ValidateExcuse) и определяется действие для
+// каждой; если в режиме strict есть некорректные записи или при
+// ConflictFail есть конфликты, ничего не записывается. Записи пишутся по
+// одной, без общей транзакции: при ошибке хранилища уже записанные остаются.
+// Голоса записываются через RateExcuse, так что рейтинг созданных и
+// обновленных записей становится суммой их голосов; запись без голосов
+// сохраняет рейтинг из файла до первого голоса.
+func Import(ctx context.Context, store storage.Storage, r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	if !ValidConflictPolicy(opts.OnConflict) {
+		return nil, fmt.Errorf("unknown conflict policy %q", opts.OnConflict)
+	}
+
+	records, err := Decode(r, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{DryRun: opts.DryRun, Total: len(records)}
+	valid := make([]models.Excuse, 0, len(records))
+	votes := make(map[string]map[string]int, len(records))
+	firstIndex := make(map[string]int, len(records))
+
+	for _, rec := range records {
+		var reasons []string
+		if rec.Err != nil {
+			reasons = []string{rec.Err.Error()}
+		} else {
+			reasons = storage.ValidateExcuse(&rec.Excuse, opts.Catalog)
+			reasons = append(reasons, validateVotes(rec.Votes)...)
+			if first, ok := firstIndex[rec.Excuse.ID]; ok && rec.Excuse.ID != "" {
+				reasons = append(reasons, fmt.Sprintf("duplicate id, first seen at index %d", first))
+			} else if rec.Excuse.ID != "" {
+				firstIndex[rec.Excuse.ID] = rec.Index
+			}
+		}
+
+		if len(reasons) > 0 {
+			report.Errors = append(report.Errors, storage.RecordError{
+				Index: rec.Index, ID: rec.Excuse.ID, Reason: strings.Join(reasons, "; "),
+			})
+			continue
+		}
+		valid = append(valid, rec.Excuse)
+		votes[rec.Excuse.ID] = rec.Votes
+	}
+	report.Invalid = len(report.Errors)
+	if report.Invalid > 0 && opts.Mode != storage.LoadLenient {
+		return report, storage.Invalidf("%d of %d record(s) are invalid", report.Invalid, report.Total)
+	}
+
+	// план: какие записи создать, какие обновить
+	var toCreate, toUpdate []models.Excuse
+	for _, excuse := range valid {
+		_, err := store.GetExcuse(ctx, excuse.ID)
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			toCreate = append(toCreate, excuse)
+		case err != nil:
+			return report, fmt.Errorf("failed to check excuse %s: %w", excuse.ID, err)
+		default:
+			report.Conflict = append(report.Conflict, excuse.ID)
+			if opts.OnConflict == ConflictUpsert {
+				toUpdate = append(toUpdate, excuse)
+			}
+		}
+	}
+	if opts.OnConflict == ConflictFail && len(report.Conflict) > 0 {
+		return report, fmt.Errorf("%w: %s", ErrConflicts, strings.Join(report.Conflict, ", "))
+	}
+	if opts.OnConflict == ConflictSkip {
+		report.Skipped = len(report.Conflict)
+	}
+
+	if opts.DryRun {
+		report.Created = len(toCreate)
+		report.Updated = len(toUpdate)
+		for _, excuse := range append(toCreate, toUpdate...) {
+			report.Votes += len(votes[excuse.ID])
+		}
+		return report, nil
+	}
+
+	for _, excuse := range toCreate {
+		if err := store.CreateExcuse(ctx, excuse); err != nil {
+			return report, fmt.Errorf("failed to create excuse %s: %w", excuse.ID, err)
+		}
+		report.Created++
+		if err := importVotes(ctx, store, excuse.ID, votes[excuse.ID], report); err != nil {
+			return report, err
+		}
+	}
+	for _, excuse := range toUpdate {
+		if _, err := store.UpdateExcuse(ctx, excuse); err != nil {
+			return report, fmt.Errorf("failed to update excuse %s: %w", excuse.ID, err)
+		}
+		report.Updated++
+		if err := importVotes(ctx, store, excuse.ID, votes[excuse.ID], report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// validateVotes проверяет голоса записи: непустой голосующий, голос 1 или -1
+func validateVotes(votes map[string]int) []string {
+	var reasons []string
+	for voter, vote := range votes {
+		if voter == "" {
+			reasons = append(reasons, "vote with empty voter")
+		}
+		if vote != 1 && vote != -1 {
+			reasons = append(reasons, fmt.Sprintf("vote of %q must be 1 or -1, got %d", voter, vote))
+		}
+	}
+	sort.Strings(reasons)
+	return reasons
+}
+
+func importVotes(ctx context.Context, store storage.Storage, id string, votes map[string]int, report *ImportReport) error {
+	for voter, vote := range votes {
+		if _, err := store.RateExcuse(ctx, id, voter, vote); err != nil {
+			return fmt.Errorf("failed to import votes for %s: %w", id, err)
+		}
+		report.Votes++
+	}
+	return nil
+}
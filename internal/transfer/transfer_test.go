@@ -0,0 +1,184 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"procrastigo/internal/catalog"
+	"procrastigo/internal/models"
+	"procrastigo/internal/storage"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCatalog(t *testing.T) *catalog.Catalog {
+	t.Helper()
+	cat, err := catalog.Load("../../configs/catalog.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cat
+}
+
+func newStore(t *testing.T, excuses ...models.Excuse) storage.Storage {
+	t.Helper()
+	store := storage.NewMemoryStorage()
+	for _, excuse := range excuses {
+		if err := store.CreateExcuse(context.Background(), excuse); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func excuse(id, text string, day int) models.Excuse {
+	return models.Excuse{
+		ID:        id,
+		Text:      text,
+		Category:  "work",
+		Language:  "en",
+		Severity:  "low",
+		CreatedAt: time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC),
+		Rating:    day,
+		Status:    models.StatusApproved,
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	first := excuse("a", "first, with \"quotes\"", 1)
+	first.Tags = []string{"deploy", "meetings"}
+	first.Status, first.RejectionReason = models.StatusRejected, "off-topic"
+	first.CreatedAt = first.CreatedAt.Add(123456789 * time.Nanosecond)
+	src := newStore(t, first, excuse("b", "second\nline", 2))
+	// рейтинг b (2) - сумма голосов, которые должны переехать вместе с ним
+	for _, voter := range []string{"ip:1", "key:2"} {
+		if _, err := src.RateExcuse(ctx, "b", voter, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, format := range []string{FormatJSON, FormatCSV, FormatNDJSON} {
+		var buf bytes.Buffer
+		count, err := Export(ctx, src, &buf, format)
+		if err != nil || count != 2 {
+			t.Fatalf("%s: export returned %d, %v", format, count, err)
+		}
+
+		dst := newStore(t)
+		report, err := Import(ctx, dst, &buf, ImportOptions{
+			Format: format, OnConflict: ConflictFail, Catalog: testCatalog(t),
+		})
+		if err != nil {
+			t.Fatalf("%s: import: %v", format, err)
+		}
+		if report.Created != 2 {
+			t.Errorf("%s: created %d, want 2", format, report.Created)
+		}
+
+		for _, want := range []models.Excuse{first, excuse("b", "second\nline", 2)} {
+			got, err := dst.GetExcuse(ctx, want.ID)
+			if err != nil {
+				t.Fatalf("%s: %v", format, err)
+			}
+			if !reflect.DeepEqual(*got, want) {
+				t.Errorf("%s: got %+v, want %+v", format, *got, want)
+			}
+		}
+		if votes, err := dst.GetVotes(ctx, "b"); err != nil || !reflect.DeepEqual(votes, map[string]int{"ip:1": 1, "key:2": 1}) {
+			t.Errorf("%s: votes %v, %v", format, votes, err)
+		}
+		// следующий голос продолжает рейтинг, а не начинает с нуля
+		if rating, err := dst.RateExcuse(ctx, "b", "ip:3", 1); err != nil || rating != 3 {
+			t.Errorf("%s: rating after a new vote %d, %v; want 3", format, rating, err)
+		}
+	}
+}
+
+func TestImportConflictPolicies(t *testing.T) {
+	ctx := context.Background()
+	input := `{"id":"a","text":"changed","category":"tech","language":"en","severity":"high","created_at":"2030-01-01T00:00:00Z"}
+{"id":"new","text":"new one","category":"work","language":"ru","severity":"low","created_at":"2024-01-01T00:00:00Z"}
+`
+	cases := []struct {
+		policy           string
+		dryRun           bool
+		created, updated int
+		skipped          int
+		wantErr          error
+		wantText         string
+	}{
+		{ConflictUpsert, false, 1, 1, 0, nil, "changed"},
+		{ConflictSkip, false, 1, 0, 1, nil, "original"},
+		{ConflictFail, false, 0, 0, 0, ErrConflicts, "original"},
+		{ConflictUpsert, true, 1, 1, 0, nil, "original"},
+	}
+
+	for _, tc := range cases {
+		store := newStore(t, excuse("a", "original", 5))
+		report, err := Import(ctx, store, strings.NewReader(input), ImportOptions{
+			Format: FormatNDJSON, OnConflict: tc.policy, Catalog: testCatalog(t), DryRun: tc.dryRun,
+		})
+		name := tc.policy
+		if tc.dryRun {
+			name += " dry run"
+		}
+		if !errors.Is(err, tc.wantErr) {
+			t.Fatalf("%s: got error %v, want %v", name, err, tc.wantErr)
+		}
+		if report.Created != tc.created || report.Updated != tc.updated || report.Skipped != tc.skipped {
+			t.Errorf("%s: got %s", name, report)
+		}
+
+		got, err := store.GetExcuse(ctx, "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Text != tc.wantText {
+			t.Errorf("%s: text %q, want %q", name, got.Text, tc.wantText)
+		}
+		if tc.wantText == "changed" && (got.Rating != 5 || got.CreatedAt.Day() != 5) {
+			t.Errorf("%s: upsert must keep rating and created_at, got %+v", name, got)
+		}
+
+		_, err = store.GetExcuse(ctx, "new")
+		if created := err == nil; created != (tc.created == 1 && !tc.dryRun) {
+			t.Errorf("%s: record \"new\" created=%v", name, created)
+		}
+	}
+}
+
+func TestImportInvalidRecords(t *testing.T) {
+	ctx := context.Background()
+	input := "id,text,category,language,severity,created_at\n" +
+		"ok,fine,work,en,low,2024-01-01T00:00:00Z\n" +
+		"bad,,work,en,low,2024-01-01T00:00:00Z\n" +
+		"ok,dup,work,en,low,2024-01-01T00:00:00Z\n" +
+		"date,text,work,en,low,yesterday\n"
+
+	store := newStore(t)
+	report, err := Import(ctx, store, strings.NewReader(input), ImportOptions{
+		Format: FormatCSV, OnConflict: ConflictFail, Mode: storage.LoadStrict, Catalog: testCatalog(t),
+	})
+	if !errors.Is(err, storage.ErrInvalid) || report.Invalid != 3 || report.Created != 0 {
+		t.Fatalf("strict: got %v, %+v", err, report)
+	}
+
+	report, err = Import(ctx, store, strings.NewReader(input), ImportOptions{
+		Format: FormatCSV, OnConflict: ConflictFail, Mode: storage.LoadLenient, Catalog: testCatalog(t),
+	})
+	if err != nil || report.Invalid != 3 || report.Created != 1 {
+		t.Fatalf("lenient: got %v, %+v", err, report)
+	}
+}
+
+func TestDecodeCSVRejectsUnknownColumns(t *testing.T) {
+	if _, err := Decode(strings.NewReader("id,text,author\n"), FormatCSV); err == nil {
+		t.Error("unknown column accepted")
+	}
+	if _, err := Decode(strings.NewReader("text\n"), FormatCSV); err == nil {
+		t.Error("header without id accepted")
+	}
+}
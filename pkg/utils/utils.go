@@ -7,7 +7,6 @@ import (
 	"math/rand"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 )
 
@@ -24,47 +23,37 @@ func RandomInt(max int) int {
 	return rand.Intn(max)
 }
 
+// RandomFloat генерирует случайное число в диапазоне [0, 1).
+func RandomFloat() float64 {
+	return rand.Float64()
+}
+
 // GetStartOfDay возвращает начало текущего дня (UTC).
 func GetStartOfDay() time.Time {
-	now := time.Now().UTC()
-	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return StartOfDayIn(time.Now(), time.UTC)
 }
 
-// GenerateID создает уникальный ID с префиксом.
-func GenerateID(prefix string) string {
-	timestamp := time.Now().UnixNano()
-	return fmt.Sprintf("%s-%d", prefix, timestamp)
+// StartOfDayIn возвращает начало календарного дня, в который попадает t
+// в часовом поясе loc.
+func StartOfDayIn(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
 }
 
-// --- Validation and Parsing ---
-
-// ValidateLanguage проверяет, является ли язык допустимым.
-func ValidateLanguage(lang string) bool {
-	validLangs := map[string]bool{"ru": true, "en": true}
-	return validLangs[strings.ToLower(lang)]
+// NextMidnightIn возвращает начало следующего календарного дня после t
+// в часовом поясе loc (с учетом перехода на летнее время).
+func NextMidnightIn(t time.Time, loc *time.Location) time.Time {
+	start := StartOfDayIn(t, loc)
+	return time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, loc)
 }
 
-// ValidateCategory проверяет, является ли категория допустимой.
-func ValidateCategory(category string) bool {
-	validCats := map[string]bool{
-		"general": true,
-		"work":    true,
-		"study":   true,
-		"social":  true,
-		"health":  true,
-	}
-	return validCats[strings.ToLower(category)]
+// GenerateID создает уникальный ID с префиксом.
+func GenerateID(prefix string) string {
+	timestamp := time.Now().UnixNano()
+	return fmt.Sprintf("%s-%d", prefix, timestamp)
 }
 
-// ValidateSeverity проверяет, является ли важность допустимой.
-func ValidateSeverity(severity string) bool {
-	validSeverities := map[string]bool{
-		"low":    true,
-		"medium": true,
-		"high":   true,
-	}
-	return validSeverities[strings.ToLower(severity)]
-}
+// --- Parsing ---
 
 // ParseLimit парсит строковый лимит в целое число, используя значение по умолчанию.
 func ParseLimit(limitStr string, defaultLimit int) int {